@@ -4,19 +4,22 @@ import (
 	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
-	"crypto/rand"
+	"crypto/subtle"
 	"encoding/base64"
-	"errors"
+	"fmt"
 	"io"
 )
 
 func AESEncryptCBC(plaintext, key []byte) (cipherText []byte, err error) {
-	block, _ := aes.NewCipher(key)
+	block, err := newAESCipher(key)
+	if err != nil {
+		return
+	}
 	data := pkcs7Padding(plaintext, block.BlockSize())
 
 	cipherText = make([]byte, aes.BlockSize+len(data))
 	iv := cipherText[:aes.BlockSize]
-	if _, err = io.ReadFull(rand.Reader, iv); err != nil {
+	if _, err = io.ReadFull(RandReader(), iv); err != nil {
 		return
 	}
 
@@ -34,19 +37,22 @@ func AESEncryptCBCBase64(plaintext, key string) (cipherText string, err error) {
 
 func AESDecryptCBC(cipherText, key []byte) (plaintext []byte, err error) {
 
-	block, _ := aes.NewCipher(key)
-	iv := cipherText[:aes.BlockSize]
-	cipherText = cipherText[aes.BlockSize:]
-	if len(cipherText)%aes.BlockSize != 0 {
-		err = errors.New("cipherText is not a multiple of the block size")
+	block, err := newAESCipher(key)
+	if err != nil {
+		return
+	}
+	if len(cipherText) < 2*aes.BlockSize || len(cipherText)%aes.BlockSize != 0 {
+		err = fmt.Errorf("%w: cipherText is not a multiple of the block size", ErrInvalidCiphertext)
 		return
 	}
+	iv := cipherText[:aes.BlockSize]
+	cipherText = cipherText[aes.BlockSize:]
 
 	plaintext = make([]byte, len(cipherText))
 	mode := cipher.NewCBCDecrypter(block, iv)
 	mode.CryptBlocks(plaintext, cipherText)
 
-	plaintext = pkcs7UnPadding(plaintext)
+	plaintext, err = pkcs7UnPadding(plaintext, aes.BlockSize)
 	return
 
 }
@@ -54,6 +60,7 @@ func AESDecryptCBC(cipherText, key []byte) (plaintext []byte, err error) {
 func AESDecryptCBCBase64(cipherText, key string) (plaintext string, err error) {
 	_data, err := base64.StdEncoding.DecodeString(cipherText)
 	if err != nil {
+		err = fmt.Errorf("%w: %v", ErrInvalidCiphertext, err)
 		return
 	}
 	data, err := AESDecryptCBC(_data, []byte(key))
@@ -62,12 +69,15 @@ func AESDecryptCBCBase64(cipherText, key string) (plaintext string, err error) {
 }
 
 func AESEncryptCTR(plaintext, key []byte) (cipherText []byte, err error) {
-	block, _ := aes.NewCipher(key)
+	block, err := newAESCipher(key)
+	if err != nil {
+		return
+	}
 	data := pkcs7Padding(plaintext, block.BlockSize())
 
 	cipherText = make([]byte, aes.BlockSize+len(data))
 	iv := cipherText[:aes.BlockSize]
-	if _, err = io.ReadFull(rand.Reader, iv); err != nil {
+	if _, err = io.ReadFull(RandReader(), iv); err != nil {
 		return
 	}
 
@@ -79,29 +89,35 @@ func AESEncryptCTR(plaintext, key []byte) (cipherText []byte, err error) {
 
 func AESDecryptCTR(cipherText, key []byte) (plaintext []byte, err error) {
 
-	block, _ := aes.NewCipher(key)
-	iv := cipherText[:aes.BlockSize]
-	cipherText = cipherText[aes.BlockSize:]
-	if len(cipherText)%aes.BlockSize != 0 {
-		err = errors.New("cipherText is not a multiple of the block size")
+	block, err := newAESCipher(key)
+	if err != nil {
 		return
 	}
+	if len(cipherText) < 2*aes.BlockSize || len(cipherText)%aes.BlockSize != 0 {
+		err = fmt.Errorf("%w: cipherText is not a multiple of the block size", ErrInvalidCiphertext)
+		return
+	}
+	iv := cipherText[:aes.BlockSize]
+	cipherText = cipherText[aes.BlockSize:]
 
 	plaintext = make([]byte, len(cipherText))
 	mode := cipher.NewCTR(block, iv)
 	mode.XORKeyStream(plaintext, cipherText)
-	plaintext = pkcs7UnPadding(plaintext)
+	plaintext, err = pkcs7UnPadding(plaintext, aes.BlockSize)
 
 	return
 }
 
 func AESEncryptOFB(plaintext, key []byte) (cipherText []byte, err error) {
-	block, _ := aes.NewCipher(key)
+	block, err := newAESCipher(key)
+	if err != nil {
+		return
+	}
 	data := pkcs7Padding(plaintext, block.BlockSize())
 
 	cipherText = make([]byte, aes.BlockSize+len(data))
 	iv := cipherText[:aes.BlockSize]
-	if _, err = io.ReadFull(rand.Reader, iv); err != nil {
+	if _, err = io.ReadFull(RandReader(), iv); err != nil {
 		return
 	}
 
@@ -113,29 +129,35 @@ func AESEncryptOFB(plaintext, key []byte) (cipherText []byte, err error) {
 
 func AESDecryptOFB(cipherText, key []byte) (plaintext []byte, err error) {
 
-	block, _ := aes.NewCipher(key)
-	iv := cipherText[:aes.BlockSize]
-	cipherText = cipherText[aes.BlockSize:]
-	if len(cipherText)%aes.BlockSize != 0 {
-		err = errors.New("cipherText is not a multiple of the block size")
+	block, err := newAESCipher(key)
+	if err != nil {
+		return
+	}
+	if len(cipherText) < 2*aes.BlockSize || len(cipherText)%aes.BlockSize != 0 {
+		err = fmt.Errorf("%w: cipherText is not a multiple of the block size", ErrInvalidCiphertext)
 		return
 	}
+	iv := cipherText[:aes.BlockSize]
+	cipherText = cipherText[aes.BlockSize:]
 
 	plaintext = make([]byte, len(cipherText))
 	mode := cipher.NewOFB(block, iv)
 	mode.XORKeyStream(plaintext, cipherText)
-	plaintext = pkcs7UnPadding(plaintext)
+	plaintext, err = pkcs7UnPadding(plaintext, aes.BlockSize)
 
 	return
 }
 
 func AESEncryptCFB(plaintext, key []byte) (cipherText []byte, err error) {
-	block, _ := aes.NewCipher(key)
+	block, err := newAESCipher(key)
+	if err != nil {
+		return
+	}
 	data := pkcs7Padding(plaintext, block.BlockSize())
 
 	cipherText = make([]byte, aes.BlockSize+len(data))
 	iv := cipherText[:aes.BlockSize]
-	if _, err = io.ReadFull(rand.Reader, iv); err != nil {
+	if _, err = io.ReadFull(RandReader(), iv); err != nil {
 		return
 	}
 
@@ -147,28 +169,37 @@ func AESEncryptCFB(plaintext, key []byte) (cipherText []byte, err error) {
 
 func AESDecryptCFB(cipherText, key []byte) (plaintext []byte, err error) {
 
-	block, _ := aes.NewCipher(key)
-	iv := cipherText[:aes.BlockSize]
-	cipherText = cipherText[aes.BlockSize:]
-	if len(cipherText)%aes.BlockSize != 0 {
-		err = errors.New("cipherText is not a multiple of the block size")
+	block, err := newAESCipher(key)
+	if err != nil {
+		return
+	}
+	if len(cipherText) < 2*aes.BlockSize || len(cipherText)%aes.BlockSize != 0 {
+		err = fmt.Errorf("%w: cipherText is not a multiple of the block size", ErrInvalidCiphertext)
 		return
 	}
+	iv := cipherText[:aes.BlockSize]
+	cipherText = cipherText[aes.BlockSize:]
 
 	plaintext = make([]byte, len(cipherText))
 	mode := cipher.NewCFBDecrypter(block, iv)
 	mode.XORKeyStream(plaintext, cipherText)
-	plaintext = pkcs7UnPadding(plaintext)
+	plaintext, err = pkcs7UnPadding(plaintext, aes.BlockSize)
 
 	return
 }
 
 func AESEncryptGCM(plaintext, key []byte) (cipherText []byte, err error) {
-	block, _ := aes.NewCipher(key)
-	aesgcm, _ := cipher.NewGCM(block)
+	block, err := newAESCipher(key)
+	if err != nil {
+		return
+	}
+	aesgcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return
+	}
 
 	nonce := make([]byte, aesgcm.NonceSize())
-	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+	if _, err = io.ReadFull(RandReader(), nonce); err != nil {
 		return
 	}
 
@@ -185,16 +216,29 @@ func AESEncryptGCMBase64(plaintext, key string) (cipherText string, err error) {
 
 func AESDecryptGCM(cipherText, key []byte) (plaintext []byte, err error) {
 
-	block, _ := aes.NewCipher(key)
-	aesgcm, _ := cipher.NewGCM(block)
+	block, err := newAESCipher(key)
+	if err != nil {
+		return
+	}
+	aesgcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return
+	}
+	if len(cipherText) < aesgcm.NonceSize()+aesgcm.Overhead() {
+		err = fmt.Errorf("%w: cipherText too short", ErrInvalidCiphertext)
+		return
+	}
 	nonce, cipherText := cipherText[:aesgcm.NonceSize()], cipherText[aesgcm.NonceSize():]
-	plaintext, err = aesgcm.Open(nil, nonce, cipherText, nil)
+	if plaintext, err = aesgcm.Open(nil, nonce, cipherText, nil); err != nil {
+		err = ErrAuthenticationFailed
+	}
 	return
 }
 
 func AESDecryptGCMBase64(cipherText, key string) (plaintext string, err error) {
 	_data, err := base64.StdEncoding.DecodeString(cipherText)
 	if err != nil {
+		err = fmt.Errorf("%w: %v", ErrInvalidCiphertext, err)
 		return
 	}
 	data, err := AESDecryptGCM(_data, []byte(key))
@@ -202,14 +246,32 @@ func AESDecryptGCMBase64(cipherText, key string) (plaintext string, err error) {
 
 }
 
+// newAESCipher 创建 AES block, 密钥长度必须是 16/24/32
+func newAESCipher(key []byte) (cipher.Block, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKeySize, err)
+	}
+	return block, nil
+}
+
 func pkcs7Padding(src []byte, blockSize int) []byte {
 	padding := blockSize - len(src)%blockSize
 	padText := bytes.Repeat([]byte{byte(padding)}, padding)
 	return append(src, padText...)
 }
 
-func pkcs7UnPadding(src []byte) []byte {
+func pkcs7UnPadding(src []byte, blockSize int) ([]byte, error) {
 	length := len(src)
+	if length == 0 {
+		return nil, ErrInvalidPadding
+	}
 	unPadding := int(src[length-1])
-	return src[:(length - unPadding)]
+	if unPadding == 0 || unPadding > blockSize || unPadding > length {
+		return nil, ErrInvalidPadding
+	}
+	if subtle.ConstantTimeCompare(src[length-unPadding:], bytes.Repeat([]byte{byte(unPadding)}, unPadding)) != 1 {
+		return nil, ErrInvalidPadding
+	}
+	return src[:(length - unPadding)], nil
 }
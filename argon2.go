@@ -1,7 +1,6 @@
 package crab
 
 import (
-	"crypto/rand"
 	"crypto/subtle"
 	"encoding/base64"
 	"errors"
@@ -49,42 +48,26 @@ func Argon2GenerateSaltedHashWithType(password string, typ string) (string, erro
 // 生成密钥带类型和设置
 func Argon2GenerateSaltedHashWithTypeAndOpt(password string, typ string, opt Opt) (string, error) {
 	if len(password) == 0 {
-		return "", errors.New("Password length cannot be 0")
+		return "", fmt.Errorf("%w: password length cannot be 0", ErrEmptyInput)
 	}
 
-	saltLen := opt.SaltLen
-	argon2Time := opt.Time
-	argon2Memory := opt.Memory
-	argon2Threads := opt.Threads
-	argon2KeyLen := opt.KeyLen
-
-	salt, _ := generateSalt(saltLen)
+	salt, err := generateSalt(opt.SaltLen)
+	if err != nil {
+		return "", err
+	}
 
-	var unencodedPassword []byte
-	switch typ {
-	case "argon2id":
-		unencodedPassword = argon2.IDKey(
-			[]byte(password), []byte(salt),
-			argon2Time, argon2Memory,
-			argon2Threads, argon2KeyLen,
-		)
-	case "argon2i", "argon2":
-		unencodedPassword = argon2.Key(
-			[]byte(password), []byte(salt),
-			argon2Time, argon2Memory,
-			argon2Threads, argon2KeyLen,
-		)
-	default:
-		return "", errors.New("Invalid Hash Type")
+	unencodedPassword, err := argon2Key(typ, []byte(password), []byte(salt), opt)
+	if err != nil {
+		return "", err
 	}
 
 	encodedPassword := base64.StdEncoding.EncodeToString(unencodedPassword)
 
 	hash := fmt.Sprintf(
 		"%s$%d$%d$%d$%d$%s$%s",
-		typ, argon2Time,
-		argon2Memory, argon2Threads,
-		argon2KeyLen, salt,
+		typ, opt.Time,
+		opt.Memory, opt.Threads,
+		opt.KeyLen, salt,
 		encodedPassword,
 	)
 
@@ -94,51 +77,73 @@ func Argon2GenerateSaltedHashWithTypeAndOpt(password string, typ string, opt Opt
 // 验证密钥
 func Argon2CompareHashWithPassword(hash, password string) (bool, error) {
 	if len(hash) == 0 || len(password) == 0 {
-		return false, errors.New("Arguments cannot be zero length")
+		return false, fmt.Errorf("%w: arguments cannot be zero length", ErrEmptyInput)
 	}
 
 	hashParts := strings.Split(hash, "$")
 	if len(hashParts) != 7 {
-		return false, errors.New("Invalid Data Len")
+		return false, fmt.Errorf("%w: expected 7 segments, got %d", ErrInvalidHashFormat, len(hashParts))
 	}
 
-	passwordType := hashParts[0]
-	time, _ := strconv.Atoi((hashParts[1]))
-	memory, _ := strconv.Atoi(hashParts[2])
-	threads, _ := strconv.Atoi(hashParts[3])
-	keyLen, _ := strconv.Atoi(hashParts[4])
+	passwordType, opt, err := parseArgon2Opt(hashParts)
+	if err != nil {
+		return false, err
+	}
 	salt := []byte(hashParts[5])
-	key, _ := base64.StdEncoding.DecodeString(hashParts[6])
+	key, err := base64.StdEncoding.DecodeString(hashParts[6])
+	if err != nil {
+		return false, fmt.Errorf("%w: %v", ErrInvalidHashFormat, err)
+	}
 
-	var calculatedKey []byte
-	switch passwordType {
-	case "argon2id":
-		calculatedKey = argon2.IDKey(
-			[]byte(password), salt,
-			uint32(time), uint32(memory),
-			uint8(threads), uint32(keyLen),
-		)
-	case "argon2i", "argon2":
-		calculatedKey = argon2.Key(
-			[]byte(password), salt,
-			uint32(time), uint32(memory),
-			uint8(threads), uint32(keyLen),
-		)
-	default:
-		return false, errors.New("Invalid Password Hash")
+	calculatedKey, err := argon2Key(passwordType, []byte(password), salt, opt)
+	if err != nil {
+		return false, err
 	}
 
 	if subtle.ConstantTimeCompare(key, calculatedKey) != 1 {
-		return false, errors.New("Password did not match")
+		return false, ErrPasswordMismatch
 	}
 
 	return true, nil
 }
 
+// argon2Key 按类型计算 argon2 摘要
+func argon2Key(typ string, password, salt []byte, opt Opt) ([]byte, error) {
+	switch typ {
+	case "argon2id":
+		return argon2.IDKey(password, salt, opt.Time, opt.Memory, opt.Threads, opt.KeyLen), nil
+	case "argon2i", "argon2":
+		return argon2.Key(password, salt, opt.Time, opt.Memory, opt.Threads, opt.KeyLen), nil
+	default:
+		return nil, fmt.Errorf("%w: hash type %q", ErrUnsupportedAlgorithm, typ)
+	}
+}
+
+// parseArgon2Opt 解析 hash 中的类型和参数部分
+func parseArgon2Opt(hashParts []string) (string, Opt, error) {
+	time, err1 := strconv.ParseUint(hashParts[1], 10, 32)
+	memory, err2 := strconv.ParseUint(hashParts[2], 10, 32)
+	threads, err3 := strconv.ParseUint(hashParts[3], 10, 8)
+	keyLen, err4 := strconv.ParseUint(hashParts[4], 10, 32)
+	if err := errors.Join(err1, err2, err3, err4); err != nil {
+		return "", Opt{}, fmt.Errorf("%w: %v", ErrInvalidHashFormat, err)
+	}
+	// argon2 在 time 或 threads 为 0 时会 panic, keyLen 为 0 时任何密码都能匹配
+	if time == 0 || threads == 0 || keyLen == 0 {
+		return "", Opt{}, fmt.Errorf("%w: time, threads and key length must be positive", ErrInvalidHashFormat)
+	}
+	return hashParts[0], Opt{
+		Time:    uint32(time),
+		Memory:  uint32(memory),
+		Threads: uint8(threads),
+		KeyLen:  uint32(keyLen),
+	}, nil
+}
+
 func generateSalt(len int) (string, error) {
 	unencodedSalt := make([]byte, len)
 
-	if _, err := rand.Read(unencodedSalt); err != nil {
+	if err := randRead(unencodedSalt); err != nil {
 		return "", err
 	}
 
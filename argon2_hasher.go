@@ -0,0 +1,290 @@
+package crab
+
+import (
+	"container/list"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Argon2HasherOpt Argon2Hasher 配置
+type Argon2HasherOpt struct {
+	// Type hash 类型, 默认 argon2id
+	Type string
+	// Opt argon2 参数, 默认 defaultOpt
+	Opt *Opt
+	// MaxMemory 同时进行的 hash 计算可使用的内存总量, 单位 KiB, 与 Opt.Memory 单位一致, 0 表示不限制
+	MaxMemory uint64
+	// MaxConcurrent 同时进行的 hash 计算数量, 0 表示不限制
+	MaxConcurrent int
+	// Timeout 排队等待的最长时间, 0 表示只受 ctx 控制
+	Timeout time.Duration
+	// Pepper 不为空时生成的 hash 带 pepper, 验证时按 hash 格式自动选择
+	Pepper *Argon2Pepper
+}
+
+// Argon2HasherStats Argon2Hasher 运行指标
+type Argon2HasherStats struct {
+	// Running 正在计算的数量
+	Running int
+	// Waiting 正在排队的数量
+	Waiting int
+	// MemoryInUse 正在计算的 hash 占用的内存, 单位 KiB
+	MemoryInUse uint64
+	// Completed 已完成计算的总数, 不包括排队期间被取消的请求
+	Completed uint64
+	// Canceled 排队期间被取消或超时的总数
+	Canceled uint64
+	// TotalWait 累计排队时间
+	TotalWait time.Duration
+	// MaxWait 最长一次排队时间
+	MaxWait time.Duration
+}
+
+// Argon2Hasher 限制内存和并发的 argon2 hasher
+// 每次 argon2 计算都会分配 Opt.Memory 的内存, 登录高峰时用它排队以免 OOM
+type Argon2Hasher struct {
+	typ    string
+	opt    Opt
+	pepper *Argon2Pepper
+
+	timeout time.Duration
+	limiter *argon2Limiter
+
+	completed atomic.Uint64
+	canceled  atomic.Uint64
+	totalWait atomic.Int64
+	maxWait   atomic.Int64
+}
+
+// NewArgon2Hasher 创建 Argon2Hasher
+func NewArgon2Hasher(opt Argon2HasherOpt) *Argon2Hasher {
+	h := &Argon2Hasher{
+		typ:     opt.Type,
+		opt:     defaultOpt,
+		pepper:  opt.Pepper,
+		timeout: opt.Timeout,
+		limiter: &argon2Limiter{
+			maxMemory:     opt.MaxMemory,
+			maxConcurrent: opt.MaxConcurrent,
+		},
+	}
+	if h.typ == "" {
+		h.typ = defaultType
+	}
+	if opt.Opt != nil {
+		h.opt = *opt.Opt
+	}
+	return h
+}
+
+// Hash 排队后生成密钥
+func (h *Argon2Hasher) Hash(ctx context.Context, password string) (string, error) {
+	if err := h.acquire(ctx, h.opt.Memory); err != nil {
+		return "", err
+	}
+	defer h.release(h.opt.Memory)
+
+	if h.pepper != nil {
+		return h.pepper.GenerateSaltedHashWithTypeAndOpt(password, h.typ, h.opt)
+	}
+	return Argon2GenerateSaltedHashWithTypeAndOpt(password, h.typ, h.opt)
+}
+
+// Compare 排队后验证密钥, 占用的内存按 hash 中记录的参数计算
+// hash 格式错误或 pepper 不可用时直接返回错误, 不进入队列
+func (h *Argon2Hasher) Compare(ctx context.Context, hash, password string) (bool, error) {
+	opt, err := h.checkHash(hash, password)
+	if err != nil {
+		return false, err
+	}
+
+	if err := h.acquire(ctx, opt.Memory); err != nil {
+		return false, err
+	}
+	defer h.release(opt.Memory)
+
+	if h.pepper != nil && strings.Count(hash, "$") == 7 {
+		return h.pepper.CompareHashWithPassword(hash, password)
+	}
+	return Argon2CompareHashWithPassword(hash, password)
+}
+
+// checkHash 在排队前完成不需要计算 argon2 的检查, 返回 hash 中记录的参数
+func (h *Argon2Hasher) checkHash(hash, password string) (Opt, error) {
+	if len(hash) == 0 || len(password) == 0 {
+		return Opt{}, fmt.Errorf("%w: arguments cannot be zero length", ErrEmptyInput)
+	}
+	hashParts := strings.Split(hash, "$")
+	if len(hashParts) != 7 && len(hashParts) != 8 {
+		return Opt{}, fmt.Errorf("%w: expected 7 or 8 segments, got %d", ErrInvalidHashFormat, len(hashParts))
+	}
+	typ, opt, err := parseArgon2Opt(hashParts)
+	if err != nil {
+		return Opt{}, err
+	}
+	switch typ {
+	case "argon2id", "argon2i", "argon2":
+	default:
+		return Opt{}, fmt.Errorf("%w: hash type %q", ErrUnsupportedAlgorithm, typ)
+	}
+	if _, err := base64.StdEncoding.DecodeString(hashParts[len(hashParts)-1]); err != nil {
+		return Opt{}, fmt.Errorf("%w: %v", ErrInvalidHashFormat, err)
+	}
+
+	if len(hashParts) == 8 {
+		if h.pepper == nil {
+			return Opt{}, fmt.Errorf("%w: hasher has no pepper configured", ErrUnknownPepper)
+		}
+		if _, ok := h.pepper.Peppers[hashParts[5]]; !ok {
+			return Opt{}, fmt.Errorf("%w: %q", ErrUnknownPepper, hashParts[5])
+		}
+	}
+	return opt, nil
+}
+
+// Stats 返回当前指标
+func (h *Argon2Hasher) Stats() Argon2HasherStats {
+	running, waiting, memory := h.limiter.stats()
+	return Argon2HasherStats{
+		Running:     running,
+		Waiting:     waiting,
+		MemoryInUse: memory,
+		Completed:   h.completed.Load(),
+		Canceled:    h.canceled.Load(),
+		TotalWait:   time.Duration(h.totalWait.Load()),
+		MaxWait:     time.Duration(h.maxWait.Load()),
+	}
+}
+
+func (h *Argon2Hasher) acquire(ctx context.Context, memory uint32) error {
+	if h.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	err := h.limiter.acquire(ctx, memory)
+	wait := int64(time.Since(start))
+	h.totalWait.Add(wait)
+	for {
+		max := h.maxWait.Load()
+		if wait <= max || h.maxWait.CompareAndSwap(max, wait) {
+			break
+		}
+	}
+	if err != nil {
+		h.canceled.Add(1)
+	}
+	return err
+}
+
+// release 归还资源, 计算已经完成
+func (h *Argon2Hasher) release(memory uint32) {
+	h.limiter.release(memory)
+	h.completed.Add(1)
+}
+
+type argon2Waiter struct {
+	memory uint64
+	ready  chan struct{}
+}
+
+// argon2Limiter 按先进先出顺序分配内存和并发数
+type argon2Limiter struct {
+	mu            sync.Mutex
+	maxMemory     uint64
+	maxConcurrent int
+	memory        uint64
+	running       int
+	waiters       list.List
+}
+
+// weight 单个 hash 超过内存上限时按上限计算, 保证它能单独执行
+func (l *argon2Limiter) weight(memory uint32) uint64 {
+	if l.maxMemory > 0 && uint64(memory) > l.maxMemory {
+		return l.maxMemory
+	}
+	return uint64(memory)
+}
+
+func (l *argon2Limiter) fits(memory uint64) bool {
+	if l.maxConcurrent > 0 && l.running >= l.maxConcurrent {
+		return false
+	}
+	if l.maxMemory > 0 && l.memory+memory > l.maxMemory {
+		return false
+	}
+	return true
+}
+
+func (l *argon2Limiter) acquire(ctx context.Context, memory uint32) error {
+	m := l.weight(memory)
+
+	l.mu.Lock()
+	if l.waiters.Len() == 0 && l.fits(m) {
+		l.memory += m
+		l.running++
+		l.mu.Unlock()
+		return nil
+	}
+
+	w := &argon2Waiter{memory: m, ready: make(chan struct{})}
+	elem := l.waiters.PushBack(w)
+	l.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		select {
+		case <-w.ready:
+			// 取消的同时拿到了资源, 归还后按取消处理
+			l.memory -= m
+			l.running--
+		default:
+			l.waiters.Remove(elem)
+		}
+		l.notify()
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+func (l *argon2Limiter) release(memory uint32) {
+	l.mu.Lock()
+	l.memory -= l.weight(memory)
+	l.running--
+	l.notify()
+	l.mu.Unlock()
+}
+
+// notify 唤醒队首可以执行的等待者, 调用时需持有锁
+func (l *argon2Limiter) notify() {
+	for {
+		front := l.waiters.Front()
+		if front == nil {
+			return
+		}
+		w := front.Value.(*argon2Waiter)
+		if !l.fits(w.memory) {
+			return
+		}
+		l.memory += w.memory
+		l.running++
+		l.waiters.Remove(front)
+		close(w.ready)
+	}
+}
+
+func (l *argon2Limiter) stats() (running, waiting int, memory uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.running, l.waiters.Len(), l.memory
+}
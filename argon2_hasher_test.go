@@ -0,0 +1,127 @@
+package crab
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/serialt/crab/internal"
+)
+
+func TestArgon2Hasher(t *testing.T) {
+	assert := internal.NewAssert(t, "TestArgon2Hasher")
+
+	opt := Opt{SaltLen: 16, Time: 1, Memory: 8 * 1024, Threads: 1, KeyLen: 32}
+	h := NewArgon2Hasher(Argon2HasherOpt{Opt: &opt, MaxMemory: 16 * 1024})
+
+	var wg sync.WaitGroup
+	hashes := make([]string, 8)
+	errs := make([]error, 8)
+	for i := range hashes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			hashes[i], errs[i] = h.Hash(context.Background(), "Password1")
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range hashes {
+		assert.IsNil(errs[i])
+		ok, err := h.Compare(context.Background(), hashes[i], "Password1")
+		assert.IsNil(err)
+		assert.Equal(true, ok)
+	}
+
+	stats := h.Stats()
+	assert.Equal(0, stats.Running)
+	assert.Equal(0, stats.Waiting)
+	assert.Equal(uint64(0), stats.MemoryInUse)
+	assert.Equal(uint64(16), stats.Completed)
+	assert.Equal(uint64(0), stats.Canceled)
+}
+
+func TestArgon2HasherTimeout(t *testing.T) {
+	assert := internal.NewAssert(t, "TestArgon2HasherTimeout")
+
+	opt := Opt{SaltLen: 16, Time: 1, Memory: 8 * 1024, Threads: 1, KeyLen: 32}
+	h := NewArgon2Hasher(Argon2HasherOpt{Opt: &opt, MaxConcurrent: 1, Timeout: 20 * time.Millisecond})
+
+	// 占满唯一的执行位
+	assert.IsNil(h.limiter.acquire(context.Background(), opt.Memory))
+
+	_, err := h.Hash(context.Background(), "Password1")
+	assert.Equal(true, errors.Is(err, context.DeadlineExceeded))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = h.Hash(ctx, "Password1")
+	assert.Equal(true, errors.Is(err, context.Canceled))
+
+	stats := h.Stats()
+	assert.Equal(1, stats.Running)
+	assert.Equal(0, stats.Waiting)
+	assert.Equal(uint64(2), stats.Canceled)
+	assert.LessOrEqual(20*time.Millisecond, stats.MaxWait)
+
+	h.limiter.release(opt.Memory)
+	_, err = h.Hash(context.Background(), "Password1")
+	assert.IsNil(err)
+	assert.Equal(uint64(1), h.Stats().Completed)
+}
+
+func TestArgon2HasherPepper(t *testing.T) {
+	assert := internal.NewAssert(t, "TestArgon2HasherPepper")
+
+	opt := Opt{SaltLen: 16, Time: 1, Memory: 8 * 1024, Threads: 1, KeyLen: 32}
+	p, err := NewArgon2Pepper("v1", map[string][]byte{"v1": []byte("pepper-one")})
+	assert.IsNil(err)
+	h := NewArgon2Hasher(Argon2HasherOpt{Opt: &opt, MaxConcurrent: 2, Pepper: p})
+
+	hash, err := h.Hash(context.Background(), "Password1")
+	assert.IsNil(err)
+	id, _ := Argon2PepperID(hash)
+	assert.Equal("v1", id)
+
+	ok, err := h.Compare(context.Background(), hash, "Password1")
+	assert.IsNil(err)
+	assert.Equal(true, ok)
+
+	_, err = NewArgon2Hasher(Argon2HasherOpt{Opt: &opt}).Compare(context.Background(), hash, "Password1")
+	assert.IsNotNil(err)
+
+	// 没有对应 pepper 的 hash 不进入队列
+	full := NewArgon2Hasher(Argon2HasherOpt{Opt: &opt, MaxConcurrent: 1})
+	assert.IsNil(full.limiter.acquire(context.Background(), opt.Memory))
+	_, err = full.Compare(context.Background(), hash, "Password1")
+	assert.Equal(true, errors.Is(err, ErrUnknownPepper))
+	other, _ := NewArgon2Pepper("v2", map[string][]byte{"v2": []byte("pepper-two")})
+	_, err = NewArgon2Hasher(Argon2HasherOpt{Opt: &opt, MaxConcurrent: 1, Pepper: other}).Compare(context.Background(), hash, "Password1")
+	assert.Equal(true, errors.Is(err, ErrUnknownPepper))
+	stats := full.Stats()
+	assert.Equal(0, stats.Waiting)
+	assert.Equal(uint64(0), stats.Completed)
+	assert.Equal(uint64(0), stats.Canceled)
+}
+
+func TestArgon2HasherMalformed(t *testing.T) {
+	assert := internal.NewAssert(t, "TestArgon2HasherMalformed")
+
+	// 占满唯一的执行位, 格式错误的 hash 不排队直接返回
+	h := NewArgon2Hasher(Argon2HasherOpt{MaxConcurrent: 1})
+	assert.IsNil(h.limiter.acquire(context.Background(), 8*1024))
+	for _, hash := range []string{
+		`argon2id$0$8192$1$32$c2FsdHNhbHRzYWx0c2FsdA==$BJzVSk9azcO/6Po+x6qWwFUFZlBy9sUsp4eSDzv20sU=`,
+		`argon2id$1$8192$0$32$c2FsdHNhbHRzYWx0c2FsdA==$BJzVSk9azcO/6Po+x6qWwFUFZlBy9sUsp4eSDzv20sU=`,
+		`argon2id$1$8192$1$32$c2FsdHNhbHRzYWx0c2FsdA==$not base64`,
+	} {
+		ok, err := h.Compare(context.Background(), hash, "Password1")
+		assert.Equal(false, ok)
+		assert.Equal(true, errors.Is(err, ErrInvalidHashFormat))
+	}
+	_, err := h.Compare(context.Background(), `scrypt$1$8192$1$32$c2FsdA==$a2V5`, "Password1")
+	assert.Equal(true, errors.Is(err, ErrUnsupportedAlgorithm))
+	assert.Equal(0, h.Stats().Waiting)
+}
@@ -0,0 +1,146 @@
+package crab
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// Argon2Pepper 服务端密钥(pepper)配置
+// 密码先经过 HMAC-SHA256(pepper, password) 再做 argon2，仅泄露数据库无法离线爆破。
+// hash 中记录 pepper ID，格式为 typ$time$memory$threads$keyLen$pepperID$salt$hash
+type Argon2Pepper struct {
+	// CurrentID 生成新 hash 时使用的 pepper ID
+	CurrentID string
+	// Peppers pepper ID 到 pepper 的映射，轮换期间旧 pepper 需要保留用于验证
+	Peppers map[string][]byte
+}
+
+// NewArgon2Pepper 创建 pepper 配置, currentID 必须存在于 peppers 中
+func NewArgon2Pepper(currentID string, peppers map[string][]byte) (*Argon2Pepper, error) {
+	p := &Argon2Pepper{CurrentID: currentID, Peppers: peppers}
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *Argon2Pepper) validate() error {
+	for id, pepper := range p.Peppers {
+		if len(id) == 0 || strings.Contains(id, "$") {
+			return fmt.Errorf("%w: invalid pepper id %q", ErrInvalidKey, id)
+		}
+		if len(pepper) == 0 {
+			return fmt.Errorf("%w: pepper %q cannot be empty", ErrInvalidKeySize, id)
+		}
+	}
+	if _, ok := p.Peppers[p.CurrentID]; !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownPepper, p.CurrentID)
+	}
+	return nil
+}
+
+// GenerateSaltedHash 使用当前 pepper 生成密钥
+func (p *Argon2Pepper) GenerateSaltedHash(password string) (string, error) {
+	return p.GenerateSaltedHashWithTypeAndOpt(password, defaultType, defaultOpt)
+}
+
+// GenerateSaltedHashWithTypeAndOpt 使用当前 pepper 生成密钥带类型和设置
+func (p *Argon2Pepper) GenerateSaltedHashWithTypeAndOpt(password string, typ string, opt Opt) (string, error) {
+	if len(password) == 0 {
+		return "", fmt.Errorf("%w: password length cannot be 0", ErrEmptyInput)
+	}
+	if err := p.validate(); err != nil {
+		return "", err
+	}
+
+	salt, err := generateSalt(opt.SaltLen)
+	if err != nil {
+		return "", err
+	}
+
+	peppered := pepperPassword(p.Peppers[p.CurrentID], password)
+	unencodedPassword, err := argon2Key(typ, peppered, []byte(salt), opt)
+	if err != nil {
+		return "", err
+	}
+
+	hash := fmt.Sprintf(
+		"%s$%d$%d$%d$%d$%s$%s$%s",
+		typ, opt.Time,
+		opt.Memory, opt.Threads,
+		opt.KeyLen, p.CurrentID, salt,
+		base64.StdEncoding.EncodeToString(unencodedPassword),
+	)
+	return hash, nil
+}
+
+// CompareHashWithPassword 根据 hash 中记录的 pepper ID 验证密钥
+func (p *Argon2Pepper) CompareHashWithPassword(hash, password string) (bool, error) {
+	if len(hash) == 0 || len(password) == 0 {
+		return false, fmt.Errorf("%w: arguments cannot be zero length", ErrEmptyInput)
+	}
+
+	hashParts := strings.Split(hash, "$")
+	if len(hashParts) != 8 {
+		return false, fmt.Errorf("%w: expected 8 segments, got %d", ErrInvalidHashFormat, len(hashParts))
+	}
+
+	pepper, ok := p.Peppers[hashParts[5]]
+	if !ok {
+		return false, fmt.Errorf("%w: %q", ErrUnknownPepper, hashParts[5])
+	}
+
+	passwordType, opt, err := parseArgon2Opt(hashParts)
+	if err != nil {
+		return false, err
+	}
+	salt := []byte(hashParts[6])
+	key, err := base64.StdEncoding.DecodeString(hashParts[7])
+	if err != nil {
+		return false, fmt.Errorf("%w: %v", ErrInvalidHashFormat, err)
+	}
+
+	calculatedKey, err := argon2Key(passwordType, pepperPassword(pepper, password), salt, opt)
+	if err != nil {
+		return false, err
+	}
+
+	if subtle.ConstantTimeCompare(key, calculatedKey) != 1 {
+		return false, ErrPasswordMismatch
+	}
+
+	return true, nil
+}
+
+// NeedsRotation 判断 hash 是否仍在使用旧的 pepper (或没有 pepper)
+// 返回 true 时应在用户下次登录验证成功后用 GenerateSaltedHash 重新生成
+func (p *Argon2Pepper) NeedsRotation(hash string) bool {
+	id, err := Argon2PepperID(hash)
+	if err != nil {
+		return true
+	}
+	return id != p.CurrentID
+}
+
+// Argon2PepperID 返回 hash 中记录的 pepper ID, 没有 pepper 的 hash 返回空字符串
+func Argon2PepperID(hash string) (string, error) {
+	hashParts := strings.Split(hash, "$")
+	switch len(hashParts) {
+	case 7:
+		return "", nil
+	case 8:
+		return hashParts[5], nil
+	default:
+		return "", fmt.Errorf("%w: expected 7 or 8 segments, got %d", ErrInvalidHashFormat, len(hashParts))
+	}
+}
+
+func pepperPassword(pepper []byte, password string) []byte {
+	h := hmac.New(sha256.New, pepper)
+	h.Write([]byte(password))
+	return h.Sum(nil)
+}
@@ -0,0 +1,76 @@
+package crab
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/serialt/crab/internal"
+)
+
+func TestArgon2Pepper(t *testing.T) {
+	assert := internal.NewAssert(t, "TestArgon2Pepper")
+
+	_, err := NewArgon2Pepper("v2", map[string][]byte{"v1": []byte("pepper-one")})
+	assert.IsNotNil(err)
+	_, err = NewArgon2Pepper("v$1", map[string][]byte{"v$1": []byte("pepper-one")})
+	assert.IsNotNil(err)
+
+	p, err := NewArgon2Pepper("v1", map[string][]byte{"v1": []byte("pepper-one")})
+	assert.IsNil(err)
+
+	hash, err := p.GenerateSaltedHash("Password1")
+	assert.IsNil(err)
+	assert.Equal(8, len(strings.Split(hash, "$")))
+
+	id, err := Argon2PepperID(hash)
+	assert.IsNil(err)
+	assert.Equal("v1", id)
+
+	ok, err := p.CompareHashWithPassword(hash, "Password1")
+	assert.IsNil(err)
+	assert.Equal(true, ok)
+
+	ok, err = p.CompareHashWithPassword(hash, "Password2")
+	assert.IsNotNil(err)
+	assert.Equal(false, ok)
+
+	// 同样的密码换一个 pepper 无法通过验证
+	other := &Argon2Pepper{CurrentID: "v1", Peppers: map[string][]byte{"v1": []byte("pepper-two")}}
+	ok, _ = other.CompareHashWithPassword(hash, "Password1")
+	assert.Equal(false, ok)
+
+	// 未配置的 pepper
+	missing := &Argon2Pepper{CurrentID: "v9", Peppers: map[string][]byte{"v9": []byte("pepper-nine")}}
+	_, err = missing.CompareHashWithPassword(hash, "Password1")
+	assert.IsNotNil(err)
+}
+
+func TestArgon2PepperRotation(t *testing.T) {
+	assert := internal.NewAssert(t, "TestArgon2PepperRotation")
+
+	p, err := NewArgon2Pepper("v1", map[string][]byte{"v1": []byte("pepper-one")})
+	assert.IsNil(err)
+	oldHash, err := p.GenerateSaltedHash("Password1")
+	assert.IsNil(err)
+	assert.Equal(false, p.NeedsRotation(oldHash))
+
+	p.Peppers["v2"] = []byte("pepper-two")
+	p.CurrentID = "v2"
+	assert.Equal(true, p.NeedsRotation(oldHash))
+
+	// 旧 pepper 保留期间仍可验证
+	ok, err := p.CompareHashWithPassword(oldHash, "Password1")
+	assert.IsNil(err)
+	assert.Equal(true, ok)
+
+	newHash, err := p.GenerateSaltedHash("Password1")
+	assert.IsNil(err)
+	assert.Equal(false, p.NeedsRotation(newHash))
+
+	plain, err := Argon2GenerateSaltedHash("Password1")
+	assert.IsNil(err)
+	assert.Equal(true, p.NeedsRotation(plain))
+	id, err := Argon2PepperID(plain)
+	assert.IsNil(err)
+	assert.Equal("", id)
+}
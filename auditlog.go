@@ -0,0 +1,334 @@
+package crab
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 审计记录类型
+const (
+	AuditEntry      = "entry"
+	AuditCheckpoint = "checkpoint"
+)
+
+// auditGenesis 第一条记录的 Prev
+var auditGenesis = strings.Repeat("0", 64)
+
+// auditSigPrefix 签名总是记录的最后一个字段, 去掉它即得到被签名的内容
+const auditSigPrefix = `,"sig":"`
+
+// AuditRecord 审计日志中的一条记录, 文件中每行一条 JSON
+type AuditRecord struct {
+	Seq    uint64          `json:"seq"`
+	Type   string          `json:"type"`
+	Time   time.Time       `json:"time"`
+	Actor  string          `json:"actor,omitempty"`
+	Action string          `json:"action,omitempty"`
+	Data   json.RawMessage `json:"data,omitempty"`
+	// Prev 上一行的 sha256, 第一条为 64 个 0
+	Prev string `json:"prev"`
+	// Sig 对不含 sig 字段的记录的签名, hex 编码
+	Sig string `json:"sig,omitempty"`
+}
+
+// AuditSigner 审计记录的签名算法
+type AuditSigner interface {
+	Sign(data []byte) ([]byte, error)
+	Verify(data, sig []byte) bool
+}
+
+// NewAuditHMAC 返回 HMAC-SHA256 签名, 签名和验证使用同一个 key
+func NewAuditHMAC(key []byte) AuditSigner {
+	return auditHMAC(key)
+}
+
+// NewAuditEd25519 返回 Ed25519 签名; 只验证时 priv 可以为 nil, 只传入 pub
+func NewAuditEd25519(priv ed25519.PrivateKey, pub ed25519.PublicKey) AuditSigner {
+	if pub == nil && priv != nil {
+		pub = priv.Public().(ed25519.PublicKey)
+	}
+	return &auditEd25519{priv: priv, pub: pub}
+}
+
+type auditHMAC []byte
+
+func (k auditHMAC) Sign(data []byte) ([]byte, error) {
+	h := hmac.New(sha256.New, k)
+	h.Write(data)
+	return h.Sum(nil), nil
+}
+
+func (k auditHMAC) Verify(data, sig []byte) bool {
+	expected, _ := k.Sign(data)
+	return hmac.Equal(expected, sig)
+}
+
+type auditEd25519 struct {
+	priv ed25519.PrivateKey
+	pub  ed25519.PublicKey
+}
+
+func (k *auditEd25519) Sign(data []byte) ([]byte, error) {
+	if len(k.priv) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("%w: ed25519 private key", ErrInvalidKey)
+	}
+	return ed25519.Sign(k.priv, data), nil
+}
+
+func (k *auditEd25519) Verify(data, sig []byte) bool {
+	return len(k.pub) == ed25519.PublicKeySize && ed25519.Verify(k.pub, data, sig)
+}
+
+// AuditLogOpt AuditLog 配置
+type AuditLogOpt struct {
+	// Signer 签名算法, 为 nil 时只有 hash 链, 不能写检查点
+	Signer AuditSigner
+	// SignEntries 为每条记录签名, 否则只为检查点签名
+	SignEntries bool
+	// CheckpointEvery 每写入这么多条记录自动写一个签名的检查点, 0 表示不自动写
+	CheckpointEvery int
+}
+
+// AuditLog 只追加的审计日志, 每条记录包含上一行的 sha256, 修改或删除任何一行都会破坏 hash 链
+// 可以并发写入, 但同一个文件只能有一个 AuditLog 写入
+type AuditLog struct {
+	path string
+	opt  AuditLogOpt
+
+	mu      sync.Mutex
+	seq     uint64
+	prev    string
+	pending int
+}
+
+// OpenAuditLog 打开审计日志, 文件已存在时从最后一行继续
+func OpenAuditLog(path string, opt AuditLogOpt) (*AuditLog, error) {
+	if opt.CheckpointEvery > 0 && opt.Signer == nil {
+		return nil, fmt.Errorf("%w: checkpoints require a signer", ErrEmptyInput)
+	}
+	l := &AuditLog{path: path, opt: opt, prev: auditGenesis}
+	if !IsExist(path) {
+		return l, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var last []byte
+	scanner := newAuditScanner(f)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) > 0 {
+			last = bytes.Clone(scanner.Bytes())
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if last != nil {
+		var rec AuditRecord
+		if err := json.Unmarshal(last, &rec); err != nil {
+			return nil, fmt.Errorf("audit log %s: malformed last record: %w", path, err)
+		}
+		l.seq, l.prev = rec.Seq, Sha256(string(last))
+	}
+	return l, nil
+}
+
+// Append 追加一条记录, data 序列化为 JSON
+func (l *AuditLog) Append(actor, action string, data any) (*AuditRecord, error) {
+	var raw json.RawMessage
+	if data != nil {
+		var err error
+		if raw, err = json.Marshal(data); err != nil {
+			return nil, err
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	rec, err := l.write(&AuditRecord{Type: AuditEntry, Actor: actor, Action: action, Data: raw}, l.opt.SignEntries)
+	if err != nil {
+		return nil, err
+	}
+	if l.pending++; l.opt.CheckpointEvery > 0 && l.pending >= l.opt.CheckpointEvery {
+		if _, err := l.checkpoint(); err != nil {
+			return rec, err
+		}
+	}
+	return rec, nil
+}
+
+// Checkpoint 写入一个签名的检查点, 签名覆盖了之前整条 hash 链
+// 把检查点的 hash 保存到日志之外, 可以发现末尾记录被截断
+func (l *AuditLog) Checkpoint() (*AuditRecord, error) {
+	if l.opt.Signer == nil {
+		return nil, fmt.Errorf("%w: checkpoints require a signer", ErrEmptyInput)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.checkpoint()
+}
+
+func (l *AuditLog) checkpoint() (*AuditRecord, error) {
+	rec, err := l.write(&AuditRecord{Type: AuditCheckpoint}, true)
+	if err == nil {
+		l.pending = 0
+	}
+	return rec, err
+}
+
+// write 填充序号、时间和 Prev 后写入一行, 调用方需持有锁
+func (l *AuditLog) write(rec *AuditRecord, sign bool) (*AuditRecord, error) {
+	rec.Seq = l.seq + 1
+	rec.Time = time.Now().UTC()
+	rec.Prev = l.prev
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+	if sign && l.opt.Signer != nil {
+		sig, err := l.opt.Signer.Sign(line)
+		if err != nil {
+			return nil, err
+		}
+		rec.Sig = hex.EncodeToString(sig)
+		line = append(line[:len(line)-1], auditSigPrefix+rec.Sig+`"}`...)
+	}
+
+	if _, err := FileAppend(l.path, append(line, '\n'), false); err != nil {
+		return nil, err
+	}
+	l.seq, l.prev = rec.Seq, Sha256(string(line))
+	return rec, nil
+}
+
+// AuditReport VerifyAuditLog 的结果
+type AuditReport struct {
+	// Records 校验通过的记录数, 包括检查点
+	Records int
+	// Checkpoints 校验通过的检查点数
+	Checkpoints int
+	// LastCheckpoint 最后一个校验通过的检查点
+	LastCheckpoint *AuditRecord
+	// Head 最后一个校验通过的行的 sha256
+	Head string
+	// BadLine 第一个被篡改或缺失的记录所在的行号, 从 1 开始, 0 表示没有问题
+	BadLine int
+	// BadSeq 第一个被篡改或缺失的记录序号
+	BadSeq uint64
+	// Unsigned 传入 signer 时, 最后一个合法签名之后没有被签名覆盖的记录数
+	// 这些记录可以连同 hash 链一起被改写而不被发现
+	Unsigned int
+	// UnsignedFrom 第一个没有被签名覆盖的记录序号, 0 表示没有
+	UnsignedFrom uint64
+	// Reason 校验失败的原因
+	Reason string
+}
+
+// Valid 所有记录都校验通过时返回 true; 传入 signer 时还要求所有记录都被签名覆盖
+func (r *AuditReport) Valid() bool {
+	return r.Reason == ""
+}
+
+// VerifyAuditLog 校验审计日志的 hash 链和签名, 定位第一个被篡改或缺失的记录
+// signer 为 nil 时只校验 hash 链, 整个文件被改写也无法发现;
+// 不为 nil 时检查点必须有合法签名, 带签名的记录也会校验签名, 签名覆盖了它之前的整条 hash 链,
+// 最后一个签名之后的记录或完全没有签名的日志记入 Unsigned, Valid 返回 false, 写完后调用 Checkpoint 可以覆盖它们
+func VerifyAuditLog(path string, signer AuditSigner) (*AuditReport, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	report := &AuditReport{Head: auditGenesis}
+	fail := func(line int, seq uint64, reason string) (*AuditReport, error) {
+		report.BadLine, report.BadSeq, report.Reason = line, seq, reason
+		return report, nil
+	}
+
+	scanner := newAuditScanner(f)
+	var expectSeq, lastSigned uint64 = 1, 0
+	lineNo, prevLineNo := 0, 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var rec AuditRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return fail(lineNo, expectSeq, "malformed record")
+		}
+		switch {
+		case rec.Seq > expectSeq:
+			return fail(lineNo, expectSeq, fmt.Sprintf("records %d to %d are missing", expectSeq, rec.Seq-1))
+		case rec.Seq < expectSeq:
+			return fail(lineNo, rec.Seq, fmt.Sprintf("unexpected sequence %d, want %d", rec.Seq, expectSeq))
+		case rec.Prev != report.Head && rec.Seq == 1:
+			// 第一条记录之前没有记录, 只能是它自己的 prev 被修改
+			return fail(lineNo, rec.Seq, fmt.Sprintf("record %d was modified", rec.Seq))
+		case rec.Prev != report.Head:
+			// 序号连续但 hash 不匹配, 说明上一行被修改
+			return fail(prevLineNo, rec.Seq-1, fmt.Sprintf("record %d was modified", rec.Seq-1))
+		}
+
+		if signer != nil && (rec.Sig != "" || rec.Type == AuditCheckpoint) {
+			// 签名必须是行尾最后一个字段, 且与 write 写入的形式完全一致,
+			// 否则可以在签名之后追加重复的键覆盖已签名的内容
+			i := bytes.LastIndex(line, []byte(auditSigPrefix))
+			sig, err := hex.DecodeString(rec.Sig)
+			if i < 0 || err != nil || string(line[i:]) != auditSigPrefix+rec.Sig+`"}` ||
+				!signer.Verify(append(bytes.Clone(line[:i]), '}'), sig) {
+				return fail(lineNo, rec.Seq, fmt.Sprintf("record %d has an invalid signature", rec.Seq))
+			}
+			lastSigned = rec.Seq
+		}
+
+		report.Records++
+		report.Head = Sha256(string(line))
+		prevLineNo = lineNo
+		if rec.Type == AuditCheckpoint {
+			report.Checkpoints++
+			report.LastCheckpoint = &rec
+		}
+		expectSeq++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if last := expectSeq - 1; signer != nil && (last > lastSigned || last == 0) {
+		if report.Unsigned = int(last - lastSigned); report.Unsigned > 0 {
+			report.UnsignedFrom = lastSigned + 1
+		}
+		if lastSigned == 0 {
+			report.Reason = "no signed checkpoint"
+		} else {
+			report.Reason = fmt.Sprintf("records %d to %d are not covered by a signature", lastSigned+1, last)
+		}
+	}
+	return report, nil
+}
+
+// newAuditScanner 按行读取, 单行最大 16 MiB
+func newAuditScanner(f *os.File) *bufio.Scanner {
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	return scanner
+}
@@ -0,0 +1,201 @@
+package crab
+
+import (
+	"crypto/ed25519"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/serialt/crab/internal"
+)
+
+func TestAuditLog(t *testing.T) {
+	assert := internal.NewAssert(t, "TestAuditLog")
+
+	path := filepath.Join(t.TempDir(), "audit", "admin.log")
+	signer := NewAuditHMAC([]byte("audit-key"))
+	log, err := OpenAuditLog(path, AuditLogOpt{Signer: signer, CheckpointEvery: 3})
+	assert.IsNil(err)
+
+	for i, action := range []string{"login", "create-user", "delete-user", "logout"} {
+		rec, err := log.Append("alice", action, map[string]int{"n": i})
+		assert.IsNil(err)
+		assert.Equal(AuditEntry, rec.Type)
+	}
+
+	// 重新打开后从最后一行继续
+	log, err = OpenAuditLog(path, AuditLogOpt{Signer: signer})
+	assert.IsNil(err)
+	_, err = log.Append("bob", "login", nil)
+	assert.IsNil(err)
+	cp, err := log.Checkpoint()
+	assert.IsNil(err)
+	assert.Equal(uint64(7), cp.Seq)
+
+	report, err := VerifyAuditLog(path, signer)
+	assert.IsNil(err)
+	assert.Equal(true, report.Valid())
+	assert.Equal(7, report.Records)
+	assert.Equal(2, report.Checkpoints)
+	assert.Equal(uint64(7), report.LastCheckpoint.Seq)
+
+	lines := readAuditLines(t, path)
+	assert.Equal(7, len(lines))
+	assert.Equal(true, strings.Contains(lines[3], `"type":"checkpoint"`))
+
+	// 修改一条记录
+	tampered := append([]string{}, lines...)
+	tampered[1] = strings.Replace(tampered[1], "create-user", "create-role", 1)
+	writeAuditLines(t, path, tampered)
+	report, err = VerifyAuditLog(path, signer)
+	assert.IsNil(err)
+	assert.Equal(false, report.Valid())
+	assert.Equal(2, report.BadLine)
+	assert.Equal(uint64(2), report.BadSeq)
+
+	// 修改第一条记录的 prev
+	tampered = append([]string{}, lines...)
+	tampered[0] = strings.Replace(tampered[0], `"prev":"`+auditGenesis, `"prev":"`+Sha256("forged"), 1)
+	writeAuditLines(t, path, tampered)
+	report, _ = VerifyAuditLog(path, signer)
+	assert.Equal(false, report.Valid())
+	assert.Equal(1, report.BadLine)
+	assert.Equal(uint64(1), report.BadSeq)
+	assert.Equal("record 1 was modified", report.Reason)
+
+	// 删除一条记录
+	writeAuditLines(t, path, append(append([]string{}, lines[:4]...), lines[5:]...))
+	report, _ = VerifyAuditLog(path, signer)
+	assert.Equal(5, report.BadLine)
+	assert.Equal(uint64(5), report.BadSeq)
+	assert.Equal("records 5 to 5 are missing", report.Reason)
+
+	// 伪造检查点签名
+	forged := append([]string{}, lines...)
+	forged[6] = forged[6][:strings.LastIndex(forged[6], `,"sig":"`)] + `,"sig":"00"}`
+	writeAuditLines(t, path, forged)
+	report, _ = VerifyAuditLog(path, signer)
+	assert.Equal(7, report.BadLine)
+	report, _ = VerifyAuditLog(path, nil)
+	assert.Equal(true, report.Valid())
+
+	_, err = OpenAuditLog(path, AuditLogOpt{CheckpointEvery: 1})
+	assert.IsNotNil(err)
+}
+
+func TestAuditLogEd25519(t *testing.T) {
+	assert := internal.NewAssert(t, "TestAuditLogEd25519")
+
+	pub, priv, err := ed25519.GenerateKey(nil)
+	assert.IsNil(err)
+	path := filepath.Join(t.TempDir(), "audit.log")
+	log, err := OpenAuditLog(path, AuditLogOpt{Signer: NewAuditEd25519(priv, nil), SignEntries: true})
+	assert.IsNil(err)
+	_, err = log.Append("alice", "rotate-key", strings.Repeat("x", 8000))
+	assert.IsNil(err)
+	_, err = log.Append("alice", "logout", nil)
+	assert.IsNil(err)
+
+	verifier := NewAuditEd25519(nil, pub)
+	report, err := VerifyAuditLog(path, verifier)
+	assert.IsNil(err)
+	assert.Equal(true, report.Valid())
+	assert.Equal(0, report.Checkpoints)
+
+	// 最后一行没有后续记录, 只能靠签名发现修改
+	lines := readAuditLines(t, path)
+	tampered := append([]string{}, lines...)
+	tampered[1] = strings.Replace(tampered[1], `"actor":"alice"`, `"actor":"mallory"`, 1)
+	writeAuditLines(t, path, tampered)
+	report, _ = VerifyAuditLog(path, verifier)
+	assert.Equal(2, report.BadLine)
+
+	// 在签名之后追加重复的键, 解析时会覆盖已签名的字段
+	tampered = append([]string{}, lines...)
+	tampered[1] = strings.TrimSuffix(tampered[1], "}") + `,"actor":"mallory","data":{"amount":99999}}`
+	writeAuditLines(t, path, tampered)
+	report, _ = VerifyAuditLog(path, verifier)
+	assert.Equal(false, report.Valid())
+	assert.Equal(2, report.BadLine)
+	assert.Equal(uint64(2), report.BadSeq)
+
+	_, err = verifier.Sign([]byte("data"))
+	assert.IsNotNil(err)
+}
+
+func TestAuditLogUnsigned(t *testing.T) {
+	assert := internal.NewAssert(t, "TestAuditLogUnsigned")
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.log")
+	signer := NewAuditHMAC([]byte("audit-key"))
+	log, err := OpenAuditLog(path, AuditLogOpt{Signer: signer, CheckpointEvery: 3})
+	assert.IsNil(err)
+	for _, action := range []string{"login", "create-user", "delete-user", "logout"} {
+		_, err = log.Append("alice", action, nil)
+		assert.IsNil(err)
+	}
+
+	// 检查点之后的记录还没有被签名覆盖
+	report, err := VerifyAuditLog(path, signer)
+	assert.IsNil(err)
+	assert.Equal(false, report.Valid())
+	assert.Equal(0, report.BadLine)
+	assert.Equal(1, report.Unsigned)
+	assert.Equal(uint64(5), report.UnsignedFrom)
+
+	_, err = log.Checkpoint()
+	assert.IsNil(err)
+	report, _ = VerifyAuditLog(path, signer)
+	assert.Equal(true, report.Valid())
+	assert.Equal(0, report.Unsigned)
+
+	// 改写最后一个检查点之后的记录并重新计算 hash 链
+	lines := readAuditLines(t, path)
+	writeAuditLines(t, path, lines[:4])
+	forger, err := OpenAuditLog(path, AuditLogOpt{})
+	assert.IsNil(err)
+	for _, action := range []string{"grant-admin", "logout"} {
+		_, err = forger.Append("mallory", action, nil)
+		assert.IsNil(err)
+	}
+	report, _ = VerifyAuditLog(path, signer)
+	assert.Equal(false, report.Valid())
+	assert.Equal(0, report.BadLine)
+	assert.Equal(2, report.Unsigned)
+	assert.Equal(uint64(5), report.UnsignedFrom)
+	assert.Equal("records 5 to 6 are not covered by a signature", report.Reason)
+
+	// 完全改写且没有检查点的日志
+	rewritten := filepath.Join(dir, "rewritten.log")
+	forger, err = OpenAuditLog(rewritten, AuditLogOpt{})
+	assert.IsNil(err)
+	for _, action := range []string{"login", "logout"} {
+		_, err = forger.Append("mallory", action, nil)
+		assert.IsNil(err)
+	}
+	report, _ = VerifyAuditLog(rewritten, signer)
+	assert.Equal(false, report.Valid())
+	assert.Equal(2, report.Unsigned)
+	assert.Equal(uint64(1), report.UnsignedFrom)
+	assert.Equal("no signed checkpoint", report.Reason)
+
+	// 只校验 hash 链时无法发现改写
+	report, _ = VerifyAuditLog(rewritten, nil)
+	assert.Equal(true, report.Valid())
+}
+
+func readAuditLines(t *testing.T, path string) []string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+func writeAuditLines(t *testing.T, path string, lines []string) {
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -20,7 +20,8 @@ func Base64StdEncode(s string) string {
 	return base64.StdEncoding.EncodeToString([]byte(s))
 }
 
-// Base64StdDecode decode a base64 encoded string.
+// Base64StdDecode decode a base64 encoded string, decoding errors are ignored.
+// Use Decode(EncodingBase64, s) to get the error.
 // Play: https://go.dev/play/p/RWQylnJVgIe
 func Base64StdDecode(s string) string {
 	b, _ := base64.StdEncoding.DecodeString(s)
@@ -1,14 +1,15 @@
 package crab
 
 import (
-	"math/rand"
+	"crypto/cipher"
+	"fmt"
 
 	"golang.org/x/crypto/chacha20poly1305"
 )
 
 // aeadEncrypt encrypts a message with a one-time key.
 func Chacha20AEADEncrypt(plaintext, key []byte) ([]byte, error) {
-	aead, err := chacha20poly1305.New(key)
+	aead, err := newChacha20AEAD(key)
 	if err != nil {
 		return nil, err
 	}
@@ -18,13 +19,24 @@ func Chacha20AEADEncrypt(plaintext, key []byte) ([]byte, error) {
 }
 
 func Chacha20AEADDecrypt(ciphertext, key []byte) ([]byte, error) {
-	aead, err := chacha20poly1305.New(key)
+	aead, err := newChacha20AEAD(key)
 	if err != nil {
 		return nil, err
 	}
 
 	nonce := make([]byte, chacha20poly1305.NonceSize)
-	return aead.Open(nil, nonce, ciphertext, nil)
+	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, ErrAuthenticationFailed
+	}
+	return plaintext, nil
+}
+
+func newChacha20AEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != chacha20poly1305.KeySize {
+		return nil, fmt.Errorf("%w: chacha20 key must be %d bytes, got %d", ErrInvalidKeySize, chacha20poly1305.KeySize, len(key))
+	}
+	return chacha20poly1305.New(key)
 }
 
 // GenerateKey aes bit: 16/24/32  chacha: 32
@@ -34,10 +46,15 @@ func GenerateKey(bit int) []byte {
 
 var letters = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
 
+// randStr 从 RandReader 生成随机字母, 随机数来源出错时 panic
 func randStr(n int) string {
 	b := make([]rune, n)
 	for i := range b {
-		b[i] = letters[rand.Intn(len(letters))]
+		idx, err := randIntn(len(letters))
+		if err != nil {
+			panic(err)
+		}
+		b[i] = letters[idx]
 	}
 	return string(b)
 }
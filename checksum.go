@@ -0,0 +1,212 @@
+package crab
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// ChecksumReport 校验清单的结果
+type ChecksumReport struct {
+	// Matched 校验通过的文件
+	Matched []string
+	// Modified 内容与清单不一致的文件
+	Modified []string
+	// Missing 清单中有但目录中不存在的文件
+	Missing []string
+	// Extra 目录中有但清单中没有的文件
+	Extra []string
+}
+
+// Valid 没有缺失、多余和被修改的文件时返回 true
+func (r *ChecksumReport) Valid() bool {
+	return len(r.Modified) == 0 && len(r.Missing) == 0 && len(r.Extra) == 0
+}
+
+// ChecksumManifest 生成目录下所有文件的 SHA256SUMS 清单, 格式与 sha256sum 输出一致
+// 文件路径为相对 dir 的路径, 使用 / 分隔并按路径排序
+func ChecksumManifest(dir string) (string, error) {
+	sums, err := checksumDir(dir, "")
+	if err != nil {
+		return "", err
+	}
+	return formatChecksumManifest(sums), nil
+}
+
+// WriteChecksumManifest 生成 dir 的清单并写入 manifest 文件
+// manifest 在 dir 中时不计入清单, 可以在 dir 下执行 sha256sum -c 校验
+func WriteChecksumManifest(dir, manifest string) error {
+	sums, err := checksumDir(dir, manifest)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(manifest, []byte(formatChecksumManifest(sums)), 0644)
+}
+
+// ParseChecksumManifest 解析 sha256sum 格式的清单, 返回文件路径到 sha256 的映射
+// 支持文本模式 "hash  name"、二进制模式 "hash *name" 和转义的文件名
+func ParseChecksumManifest(content string) (map[string]string, error) {
+	sums := map[string]string{}
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		escaped := strings.HasPrefix(line, "\\")
+		if escaped {
+			line = line[1:]
+		}
+		if len(line) < 66 || line[64] != ' ' || (line[65] != ' ' && line[65] != '*') {
+			return nil, fmt.Errorf("%w: line %d is not a sha256sum line", ErrInvalidHashFormat, i+1)
+		}
+		sum := strings.ToLower(line[:64])
+		if _, err := hex.DecodeString(sum); err != nil {
+			return nil, fmt.Errorf("%w: line %d has an invalid sha256", ErrInvalidHashFormat, i+1)
+		}
+		name := line[66:]
+		if escaped {
+			var err error
+			if name, err = unescapeChecksumName(name); err != nil {
+				return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidHashFormat, i+1, err)
+			}
+		}
+		sums[name] = sum
+	}
+	return sums, nil
+}
+
+// VerifyChecksumManifest 用 manifest 文件校验 dir, 文件并发计算 sha256
+// 返回的错误只表示清单或目录无法读取, 校验结果见 ChecksumReport
+func VerifyChecksumManifest(dir, manifest string) (*ChecksumReport, error) {
+	content, err := os.ReadFile(manifest)
+	if err != nil {
+		return nil, err
+	}
+	expected, err := ParseChecksumManifest(string(content))
+	if err != nil {
+		return nil, err
+	}
+	actual, err := checksumDir(dir, manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ChecksumReport{}
+	for name, sum := range expected {
+		got, ok := actual[name]
+		switch {
+		case !ok:
+			report.Missing = append(report.Missing, name)
+		case got != sum:
+			report.Modified = append(report.Modified, name)
+		default:
+			report.Matched = append(report.Matched, name)
+		}
+	}
+	for name := range actual {
+		if _, ok := expected[name]; !ok {
+			report.Extra = append(report.Extra, name)
+		}
+	}
+	sort.Strings(report.Matched)
+	sort.Strings(report.Modified)
+	sort.Strings(report.Missing)
+	sort.Strings(report.Extra)
+	return report, nil
+}
+
+// checksumDir 并发计算 dir 下所有文件的 sha256, 跳过 exclude 文件
+func checksumDir(dir, exclude string) (map[string]string, error) {
+	paths, err := GetFilepaths(dir)
+	if err != nil {
+		return nil, err
+	}
+	if exclude != "" {
+		if exclude, err = filepath.Abs(exclude); err != nil {
+			return nil, err
+		}
+	}
+
+	var names []string
+	var files []string
+	for _, path := range paths {
+		if exclude != "" {
+			if abs, err := filepath.Abs(path); err == nil && abs == exclude {
+				continue
+			}
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, filepath.ToSlash(rel))
+		files = append(files, path)
+	}
+
+	sums := make([]string, len(files))
+	errs := make([]error, len(files))
+	parallelDo(len(files), runtime.NumCPU(), func(i int) {
+		sums[i], errs[i] = Sha256File(files[i])
+	})
+
+	out := make(map[string]string, len(files))
+	for i, name := range names {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		out[name] = sums[i]
+	}
+	return out, nil
+}
+
+func formatChecksumManifest(sums map[string]string) string {
+	names := make([]string, 0, len(sums))
+	for name := range sums {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(formatChecksumLine(sums[name], name))
+	}
+	return b.String()
+}
+
+// formatChecksumLine 与 GNU sha256sum 一样, 文件名包含 \ 或换行时转义并在行首加 \
+func formatChecksumLine(sum, name string) string {
+	if strings.ContainsAny(name, "\\\n\r") {
+		name = strings.NewReplacer("\\", "\\\\", "\n", "\\n", "\r", "\\r").Replace(name)
+		return "\\" + sum + "  " + name + "\n"
+	}
+	return sum + "  " + name + "\n"
+}
+
+func unescapeChecksumName(name string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] != '\\' {
+			b.WriteByte(name[i])
+			continue
+		}
+		if i++; i == len(name) {
+			return "", fmt.Errorf("trailing backslash in %q", name)
+		}
+		switch name[i] {
+		case '\\':
+			b.WriteByte('\\')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		default:
+			return "", fmt.Errorf("unknown escape \\%c in %q", name[i], name)
+		}
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,90 @@
+package crab
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/serialt/crab/internal"
+)
+
+func writeChecksumTree(t *testing.T) string {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.txt":           "hello world",
+		"sub/b.bin":       "\x00\x01\x02",
+		"sub/deep/c.txt":  "crab",
+		"with space.txt":  "space",
+		"back\\slash.txt": "escaped",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestChecksumManifest(t *testing.T) {
+	assert := internal.NewAssert(t, "TestChecksumManifest")
+
+	dir := writeChecksumTree(t)
+	manifest, err := ChecksumManifest(dir)
+	assert.IsNil(err)
+
+	sums, err := ParseChecksumManifest(manifest)
+	assert.IsNil(err)
+	assert.Equal(5, len(sums))
+	assert.Equal(Sha256("hello world"), sums["a.txt"])
+	assert.Equal(Sha256("escaped"), sums["back\\slash.txt"])
+
+	// 与 sha256sum 的输出逐字节一致
+	if _, err := exec.LookPath("sha256sum"); err == nil {
+		cmd := exec.Command("sh", "-c", "find . -type f | sed 's|^\\./||' | LC_ALL=C sort | xargs -d '\\n' sha256sum --")
+		cmd.Dir = dir
+		out, err := cmd.Output()
+		assert.IsNil(err)
+		assert.Equal(string(out), manifest)
+	}
+
+	_, err = ParseChecksumManifest("not a manifest\n")
+	assert.IsNotNil(err)
+}
+
+func TestVerifyChecksumManifest(t *testing.T) {
+	assert := internal.NewAssert(t, "TestVerifyChecksumManifest")
+
+	dir := writeChecksumTree(t)
+	manifest := filepath.Join(dir, "SHA256SUMS")
+	assert.IsNil(WriteChecksumManifest(dir, manifest))
+
+	report, err := VerifyChecksumManifest(dir, manifest)
+	assert.IsNil(err)
+	assert.Equal(true, report.Valid())
+	assert.Equal(5, len(report.Matched))
+
+	if _, err := exec.LookPath("sha256sum"); err == nil {
+		cmd := exec.Command("sha256sum", "-c", "--quiet", "SHA256SUMS")
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		assert.IsNil(err)
+		assert.Equal("", string(out))
+	}
+
+	assert.IsNil(os.WriteFile(filepath.Join(dir, "a.txt"), []byte("changed"), 0644))
+	assert.IsNil(os.Remove(filepath.Join(dir, "sub", "b.bin")))
+	assert.IsNil(os.WriteFile(filepath.Join(dir, "sub", "new.txt"), []byte("new"), 0644))
+
+	report, err = VerifyChecksumManifest(dir, manifest)
+	assert.IsNil(err)
+	assert.Equal(false, report.Valid())
+	assert.Equal([]string{"a.txt"}, report.Modified)
+	assert.Equal([]string{"sub/b.bin"}, report.Missing)
+	assert.Equal([]string{"sub/new.txt"}, report.Extra)
+	assert.Equal(3, len(report.Matched))
+}
@@ -0,0 +1,171 @@
+package crab
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// duplicatePartialSize 比较前先计算文件开头这么多字节的摘要
+const duplicatePartialSize = 4096
+
+// DuplicateOpt FindDuplicatesWithOpt 的参数
+type DuplicateOpt struct {
+	// MinSize 最小文件大小, 空文件不参与比较
+	MinSize int64
+	// MaxSize 最大文件大小, 0 表示不限制
+	MaxSize int64
+	// Extensions 只比较这些后缀的文件, 如 ".jpg", 不区分大小写, 为空时比较所有文件
+	Extensions []string
+	// Workers 并发计算摘要的数量, 默认为 CPU 数
+	Workers int
+}
+
+// FindDuplicates 查找 roots 下内容相同的文件
+// 先按大小分组, 再比较开头 4 KiB 的摘要, 最后比较完整的 sha256
+// 返回的每组至少两个文件, 组内按路径排序; 符号链接会被跳过
+func FindDuplicates(roots ...string) ([][]string, error) {
+	return FindDuplicatesWithOpt(DuplicateOpt{}, roots...)
+}
+
+// FindDuplicatesWithOpt 按大小和后缀过滤后查找内容相同的文件
+func FindDuplicatesWithOpt(opt DuplicateOpt, roots ...string) ([][]string, error) {
+	exts := map[string]bool{}
+	for _, ext := range opt.Extensions {
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		exts[strings.ToLower(ext)] = true
+	}
+	workers := opt.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	seen := map[string]bool{}
+	bySize := map[int64][]string{}
+	for _, root := range roots {
+		paths, err := GetFilepaths(root)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			abs, err := filepath.Abs(path)
+			if err != nil {
+				return nil, err
+			}
+			if seen[abs] {
+				continue
+			}
+			seen[abs] = true
+
+			if len(exts) > 0 && !exts[strings.ToLower(FileExt(path))] {
+				continue
+			}
+			info, err := os.Lstat(path)
+			if err != nil {
+				return nil, err
+			}
+			size := info.Size()
+			if !info.Mode().IsRegular() || size == 0 || size < opt.MinSize || (opt.MaxSize > 0 && size > opt.MaxSize) {
+				continue
+			}
+			bySize[size] = append(bySize[size], path)
+		}
+	}
+
+	var candidates [][]string
+	for _, group := range bySize {
+		if len(group) > 1 {
+			candidates = append(candidates, group)
+		}
+	}
+
+	candidates, err := splitDuplicates(candidates, workers, partialSha256)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := splitDuplicates(candidates, workers, Sha256File)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, group := range groups {
+		sort.Strings(group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i][0] < groups[j][0]
+	})
+	return groups, nil
+}
+
+// splitDuplicates 并发计算每个文件的摘要, 把每组拆成摘要相同且至少两个文件的小组
+func splitDuplicates(groups [][]string, workers int, sum func(string) (string, error)) ([][]string, error) {
+	var files []string
+	for _, group := range groups {
+		files = append(files, group...)
+	}
+	sums := make([]string, len(files))
+	errs := make([]error, len(files))
+	parallelDo(len(files), workers, func(i int) {
+		sums[i], errs[i] = sum(files[i])
+	})
+
+	var out [][]string
+	i := 0
+	for _, group := range groups {
+		bySum := map[string][]string{}
+		for _, file := range group {
+			if errs[i] != nil {
+				return nil, errs[i]
+			}
+			bySum[sums[i]] = append(bySum[sums[i]], file)
+			i++
+		}
+		for _, same := range bySum {
+			if len(same) > 1 {
+				out = append(out, same)
+			}
+		}
+	}
+	return out, nil
+}
+
+func partialSha256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	sums, err := HashReader(context.Background(), io.LimitReader(f, duplicatePartialSize), HashOpt{})
+	if err != nil {
+		return "", err
+	}
+	return sums.Hex(HashSHA256), nil
+}
+
+// parallelDo 用 workers 个 goroutine 对 0 到 n-1 调用 fn
+func parallelDo(n, workers int, fn func(i int)) {
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
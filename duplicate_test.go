@@ -0,0 +1,62 @@
+package crab
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/serialt/crab/internal"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	assert := internal.NewAssert(t, "TestFindDuplicates")
+
+	root1, root2 := t.TempDir(), t.TempDir()
+	same := bytes.Repeat([]byte("crab"), 2048)
+	// 开头 4 KiB 相同, 后面不同
+	prefix := append(bytes.Repeat([]byte("crab"), 2047), []byte("CRAB")...)
+	files := map[string][]byte{
+		filepath.Join(root1, "a.txt"):        same,
+		filepath.Join(root1, "sub", "b.txt"): same,
+		filepath.Join(root2, "c.log"):        same,
+		filepath.Join(root2, "d.txt"):        prefix,
+		filepath.Join(root1, "e.txt"):        []byte("small"),
+		filepath.Join(root2, "f.TXT"):        []byte("small"),
+		filepath.Join(root1, "empty1"):       nil,
+		filepath.Join(root2, "empty2"):       nil,
+	}
+	for path, content := range files {
+		assert.IsNil(os.MkdirAll(filepath.Dir(path), 0755))
+		assert.IsNil(os.WriteFile(path, content, 0644))
+	}
+
+	groups, err := FindDuplicates(root1, root2, root1)
+	assert.IsNil(err)
+	assert.Equal(2, len(groups))
+	for _, group := range groups {
+		switch len(group) {
+		case 3:
+			assert.Equal([]string{
+				filepath.Join(root1, "a.txt"),
+				filepath.Join(root1, "sub", "b.txt"),
+				filepath.Join(root2, "c.log"),
+			}, group)
+		case 2:
+			assert.Equal([]string{filepath.Join(root1, "e.txt"), filepath.Join(root2, "f.TXT")}, group)
+		default:
+			t.Fatalf("unexpected group %v", group)
+		}
+	}
+
+	groups, err = FindDuplicatesWithOpt(DuplicateOpt{Extensions: []string{"txt"}, MinSize: 100, Workers: 2}, root1, root2)
+	assert.IsNil(err)
+	assert.Equal([][]string{{filepath.Join(root1, "a.txt"), filepath.Join(root1, "sub", "b.txt")}}, groups)
+
+	groups, err = FindDuplicatesWithOpt(DuplicateOpt{MaxSize: 100}, root1, root2)
+	assert.IsNil(err)
+	assert.Equal(1, len(groups))
+
+	_, err = FindDuplicates(filepath.Join(root1, "missing"))
+	assert.IsNotNil(err)
+}
@@ -0,0 +1,287 @@
+package crab
+
+import (
+	"bytes"
+	"encoding/ascii85"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Encoding 二进制到文本的编码
+type Encoding string
+
+const (
+	// EncodingBase64 RFC 4648 标准 base64, 带填充
+	EncodingBase64 Encoding = "base64"
+	// EncodingBase64URL RFC 4648 URL 安全的 base64, 带填充
+	EncodingBase64URL Encoding = "base64url"
+	// EncodingBase64Raw 标准 base64, 不带填充
+	EncodingBase64Raw Encoding = "base64raw"
+	// EncodingBase64RawURL URL 安全的 base64, 不带填充, 常用于 JWT
+	EncodingBase64RawURL Encoding = "base64rawurl"
+	// EncodingBase32 RFC 4648 标准 base32, 带填充
+	EncodingBase32 Encoding = "base32"
+	// EncodingBase32Hex RFC 4648 扩展 hex 字母表的 base32, 带填充, 编码后保持字节序
+	EncodingBase32Hex Encoding = "base32hex"
+	// EncodingBase58 比特币字母表的 base58, 去掉了容易混淆的 0OIl
+	EncodingBase58 Encoding = "base58"
+	// EncodingBase62 0-9A-Za-z 字母表的 base62, 只包含字母和数字
+	EncodingBase62 Encoding = "base62"
+	// EncodingBase85 Adobe ascii85, 不带 <~ ~> 定界符
+	EncodingBase85 Encoding = "base85"
+)
+
+const (
+	base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+	base62Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+)
+
+// Encode 用 enc 编码 data, 只有 enc 不支持时返回错误
+func Encode(enc Encoding, data []byte) (string, error) {
+	if e := stdEncoding(enc); e != nil {
+		return e.EncodeToString(data), nil
+	}
+	switch enc {
+	case EncodingBase58:
+		return baseXEncode(base58Alphabet, data), nil
+	case EncodingBase62:
+		return baseXEncode(base62Alphabet, data), nil
+	case EncodingBase85:
+		dst := make([]byte, ascii85.MaxEncodedLen(len(data)))
+		return string(dst[:ascii85.Encode(dst, data)]), nil
+	}
+	return "", fmt.Errorf("%w: encoding %q", ErrUnsupportedAlgorithm, enc)
+}
+
+// Decode 用 enc 解码 s, 输入不合法时返回 ErrInvalidEncoding, 不会像 Base64StdDecode 一样静默返回空值
+func Decode(enc Encoding, s string) ([]byte, error) {
+	if e := stdEncoding(enc); e != nil {
+		data, err := e.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidEncoding, enc, err)
+		}
+		return data, nil
+	}
+	switch enc {
+	case EncodingBase58:
+		return baseXDecode(base58Alphabet, enc, s)
+	case EncodingBase62:
+		return baseXDecode(base62Alphabet, enc, s)
+	case EncodingBase85:
+		dst := make([]byte, 4*len(s))
+		n, consumed, err := ascii85.Decode(dst, []byte(s), true)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidEncoding, enc, err)
+		}
+		if consumed != len(s) {
+			return nil, fmt.Errorf("%w: %s: trailing data at offset %d", ErrInvalidEncoding, enc, consumed)
+		}
+		return dst[:n], nil
+	}
+	return nil, fmt.Errorf("%w: encoding %q", ErrUnsupportedAlgorithm, enc)
+}
+
+// NewEncoder 返回流式编码器, 写入的数据编码后写入 w, 必须调用 Close 写出最后不完整的块
+// base58 和 base62 是整体的大数转换, 无法分块, 编码器会缓冲所有数据直到 Close
+func NewEncoder(enc Encoding, w io.Writer) (io.WriteCloser, error) {
+	if e := stdEncoding(enc); e != nil {
+		if b64, ok := e.(*base64.Encoding); ok {
+			return base64.NewEncoder(b64, w), nil
+		}
+		return base32.NewEncoder(e.(*base32.Encoding), w), nil
+	}
+	switch enc {
+	case EncodingBase58, EncodingBase62:
+		return &bufferedEncoder{enc: enc, w: w}, nil
+	case EncodingBase85:
+		return ascii85.NewEncoder(w), nil
+	}
+	return nil, fmt.Errorf("%w: encoding %q", ErrUnsupportedAlgorithm, enc)
+}
+
+// NewDecoder 返回流式解码器, 从 r 读取编码后的数据, 输入不合法时 Read 返回 ErrInvalidEncoding
+// base58 和 base62 会在第一次 Read 时读取 r 的全部数据
+func NewDecoder(enc Encoding, r io.Reader) (io.Reader, error) {
+	if e := stdEncoding(enc); e != nil {
+		if b64, ok := e.(*base64.Encoding); ok {
+			return &decodeErrReader{enc: enc, r: base64.NewDecoder(b64, r)}, nil
+		}
+		return &decodeErrReader{enc: enc, r: base32.NewDecoder(e.(*base32.Encoding), r)}, nil
+	}
+	switch enc {
+	case EncodingBase58, EncodingBase62:
+		return &bufferedDecoder{enc: enc, r: r}, nil
+	case EncodingBase85:
+		return &decodeErrReader{enc: enc, r: ascii85.NewDecoder(r)}, nil
+	}
+	return nil, fmt.Errorf("%w: encoding %q", ErrUnsupportedAlgorithm, enc)
+}
+
+// IsEncoded 检查 s 能否用 enc 解码
+func IsEncoded(enc Encoding, s string) bool {
+	_, err := Decode(enc, s)
+	return err == nil
+}
+
+type stdEncoder interface {
+	EncodeToString(src []byte) string
+	DecodeString(s string) ([]byte, error)
+}
+
+func stdEncoding(enc Encoding) stdEncoder {
+	switch enc {
+	case EncodingBase64:
+		return base64.StdEncoding.Strict()
+	case EncodingBase64URL:
+		return base64.URLEncoding.Strict()
+	case EncodingBase64Raw:
+		return base64.RawStdEncoding.Strict()
+	case EncodingBase64RawURL:
+		return base64.RawURLEncoding.Strict()
+	case EncodingBase32:
+		return base32.StdEncoding
+	case EncodingBase32Hex:
+		return base32.HexEncoding
+	}
+	return nil
+}
+
+// baseXEncode 把 data 当作大端序大数转换为 alphabet 进制, 开头的每个 0 字节编码为 alphabet[0]
+func baseXEncode(alphabet string, data []byte) string {
+	base := len(alphabet)
+	zeros := 0
+	for zeros < len(data) && data[zeros] == 0 {
+		zeros++
+	}
+
+	// 每个字节最多需要 log(256)/log(58) < 1.37 个字符
+	digits := make([]byte, 0, len(data)*138/100+1)
+	for _, b := range data[zeros:] {
+		carry := int(b)
+		for i := range digits {
+			carry += int(digits[i]) << 8
+			digits[i] = byte(carry % base)
+			carry /= base
+		}
+		for carry > 0 {
+			digits = append(digits, byte(carry%base))
+			carry /= base
+		}
+	}
+
+	out := make([]byte, zeros+len(digits))
+	for i := 0; i < zeros; i++ {
+		out[i] = alphabet[0]
+	}
+	for i, d := range digits {
+		out[len(out)-1-i] = alphabet[d]
+	}
+	return string(out)
+}
+
+func baseXDecode(alphabet string, enc Encoding, s string) ([]byte, error) {
+	base := len(alphabet)
+	var index [256]int
+	for i := range index {
+		index[i] = -1
+	}
+	for i := 0; i < base; i++ {
+		index[alphabet[i]] = i
+	}
+
+	zeros := 0
+	for zeros < len(s) && s[zeros] == alphabet[0] {
+		zeros++
+	}
+
+	var out []byte // 小端序
+	for i := zeros; i < len(s); i++ {
+		carry := index[s[i]]
+		if carry < 0 {
+			return nil, fmt.Errorf("%w: %s: illegal character %q at offset %d", ErrInvalidEncoding, enc, s[i], i)
+		}
+		for j := range out {
+			carry += int(out[j]) * base
+			out[j] = byte(carry)
+			carry >>= 8
+		}
+		for carry > 0 {
+			out = append(out, byte(carry))
+			carry >>= 8
+		}
+	}
+
+	data := make([]byte, zeros+len(out))
+	for i, b := range out {
+		data[len(data)-1-i] = b
+	}
+	return data, nil
+}
+
+type bufferedEncoder struct {
+	enc Encoding
+	w   io.Writer
+	buf bytes.Buffer
+}
+
+func (e *bufferedEncoder) Write(p []byte) (int, error) {
+	return e.buf.Write(p)
+}
+
+func (e *bufferedEncoder) Close() error {
+	s, err := Encode(e.enc, e.buf.Bytes())
+	if err != nil {
+		return err
+	}
+	e.buf.Reset()
+	_, err = io.WriteString(e.w, s)
+	return err
+}
+
+type bufferedDecoder struct {
+	enc  Encoding
+	r    io.Reader
+	data *bytes.Reader
+	// err 读取或解码的错误, 之后的每次 Read 都返回它, 不会把已读完的输入当作空数据
+	err error
+}
+
+func (d *bufferedDecoder) Read(p []byte) (int, error) {
+	if d.err != nil {
+		return 0, d.err
+	}
+	if d.data == nil {
+		raw, err := io.ReadAll(d.r)
+		if err != nil {
+			d.err = err
+			return 0, err
+		}
+		data, err := Decode(d.enc, string(bytes.TrimSpace(raw)))
+		if err != nil {
+			d.err = err
+			return 0, err
+		}
+		d.data = bytes.NewReader(data)
+	}
+	return d.data.Read(p)
+}
+
+// decodeErrReader 把标准库解码器的 CorruptInputError 包装为 ErrInvalidEncoding
+type decodeErrReader struct {
+	enc Encoding
+	r   io.Reader
+}
+
+func (d *decodeErrReader) Read(p []byte) (int, error) {
+	n, err := d.r.Read(p)
+	var b64Err base64.CorruptInputError
+	var b32Err base32.CorruptInputError
+	var a85Err ascii85.CorruptInputError
+	if errors.As(err, &b64Err) || errors.As(err, &b32Err) || errors.As(err, &a85Err) {
+		err = fmt.Errorf("%w: %s: %v", ErrInvalidEncoding, d.enc, err)
+	}
+	return n, err
+}
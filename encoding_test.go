@@ -0,0 +1,109 @@
+package crab
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/serialt/crab/internal"
+)
+
+func TestEncode(t *testing.T) {
+	assert := internal.NewAssert(t, "TestEncode")
+
+	data := []byte("hello world")
+	cases := map[Encoding]string{
+		EncodingBase64:       "aGVsbG8gd29ybGQ=",
+		EncodingBase64URL:    "aGVsbG8gd29ybGQ=",
+		EncodingBase64Raw:    "aGVsbG8gd29ybGQ",
+		EncodingBase64RawURL: "aGVsbG8gd29ybGQ",
+		EncodingBase32:       "NBSWY3DPEB3W64TMMQ======",
+		EncodingBase32Hex:    "D1IMOR3F41RMUSJCCG======",
+		EncodingBase58:       "StV1DL6CwTryKyV",
+		EncodingBase62:       "AAwf93rvy4aWQVw",
+		EncodingBase85:       "BOu!rD]j7BEbo7",
+	}
+	for enc, expected := range cases {
+		encoded, err := Encode(enc, data)
+		assert.IsNil(err)
+		assert.Equal(expected, encoded)
+
+		decoded, err := Decode(enc, encoded)
+		assert.IsNil(err)
+		assert.Equal(data, decoded)
+	}
+
+	// 开头的 0 字节
+	encoded, _ := Encode(EncodingBase58, []byte{0, 0, 1})
+	assert.Equal("112", encoded)
+	decoded, _ := Decode(EncodingBase58, "112")
+	assert.Equal([]byte{0, 0, 1}, decoded)
+	encoded, _ = Encode(EncodingBase62, []byte{0, 61})
+	assert.Equal("0z", encoded)
+
+	_, err := Encode("base36", data)
+	assert.Equal(true, errors.Is(err, ErrUnsupportedAlgorithm))
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	assert := internal.NewAssert(t, "TestDecodeInvalid")
+
+	invalid := map[Encoding]string{
+		EncodingBase64:       "aGVsbG8gd29ybGQ",
+		EncodingBase64URL:    "aGVsbG8+d29ybGQ=",
+		EncodingBase64Raw:    "aGVsbG8gd29ybGQ=",
+		EncodingBase64RawURL: "aGVsbG8/d29ybGQ",
+		EncodingBase32:       "NBSWY3DP1",
+		EncodingBase32Hex:    "WXYZ",
+		EncodingBase58:       "StV1DL6CwTry0",
+		EncodingBase62:       "AAwf93rvy4aW-Vw",
+		EncodingBase85:       "BOu!rD~",
+	}
+	for enc, s := range invalid {
+		_, err := Decode(enc, s)
+		assert.Equal(true, errors.Is(err, ErrInvalidEncoding))
+	}
+}
+
+func TestEncoderDecoder(t *testing.T) {
+	assert := internal.NewAssert(t, "TestEncoderDecoder")
+
+	data := bytes.Repeat([]byte("crab\x00\xff"), 1000)
+	for _, enc := range []Encoding{EncodingBase64, EncodingBase64RawURL, EncodingBase32Hex, EncodingBase58, EncodingBase62, EncodingBase85} {
+		var buf bytes.Buffer
+		w, err := NewEncoder(enc, &buf)
+		assert.IsNil(err)
+		for off := 0; off < len(data); off += 7 {
+			_, err := w.Write(data[off:min(off+7, len(data))])
+			assert.IsNil(err)
+		}
+		assert.IsNil(w.Close())
+
+		expected, _ := Encode(enc, data)
+		assert.Equal(expected, buf.String())
+
+		r, err := NewDecoder(enc, &buf)
+		assert.IsNil(err)
+		decoded, err := io.ReadAll(r)
+		assert.IsNil(err)
+		assert.Equal(data, decoded)
+	}
+
+	r, err := NewDecoder(EncodingBase64, strings.NewReader("aGVs!G8="))
+	assert.IsNil(err)
+	_, err = io.ReadAll(r)
+	assert.Equal(true, errors.Is(err, ErrInvalidEncoding))
+
+	r, _ = NewDecoder(EncodingBase58, strings.NewReader("0"))
+	_, err = io.ReadAll(r)
+	assert.Equal(true, errors.Is(err, ErrInvalidEncoding))
+	// 再次读取仍然返回错误, 不会把已读完的输入当作空数据
+	n, err := r.Read(make([]byte, 8))
+	assert.Equal(0, n)
+	assert.Equal(true, errors.Is(err, ErrInvalidEncoding))
+
+	_, err = NewEncoder("base36", io.Discard)
+	assert.Equal(true, errors.Is(err, ErrUnsupportedAlgorithm))
+}
@@ -0,0 +1,53 @@
+package crab
+
+import "errors"
+
+// 加解密相关的错误, 可以用 errors.Is 判断
+var (
+	// ErrEmptyInput 密码、密文、secret 等输入为空
+	ErrEmptyInput = errors.New("crab: empty input")
+	// ErrPasswordMismatch 密码不匹配
+	ErrPasswordMismatch = errors.New("crab: password did not match")
+	// ErrInvalidHashFormat hash 字符串格式错误
+	ErrInvalidHashFormat = errors.New("crab: invalid hash format")
+	// ErrUnknownPepper hash 使用的 pepper 没有配置
+	ErrUnknownPepper = errors.New("crab: unknown pepper")
+	// ErrInvalidKeySize 密钥长度错误
+	ErrInvalidKeySize = errors.New("crab: invalid key size")
+	// ErrInvalidKey 密钥无法解析
+	ErrInvalidKey = errors.New("crab: invalid key")
+	// ErrInvalidCiphertext 密文长度或格式错误
+	ErrInvalidCiphertext = errors.New("crab: invalid ciphertext")
+	// ErrAuthenticationFailed 密文认证失败, 密钥错误或数据被篡改
+	ErrAuthenticationFailed = errors.New("crab: message authentication failed")
+	// ErrInvalidPadding PKCS#7 填充错误
+	ErrInvalidPadding = errors.New("crab: invalid padding")
+	// ErrUnsupportedAlgorithm 不支持的算法或类型
+	ErrUnsupportedAlgorithm = errors.New("crab: unsupported algorithm")
+	// ErrInvalidThreshold Shamir 的份数或门限错误
+	ErrInvalidThreshold = errors.New("crab: invalid threshold")
+	// ErrInvalidShares Shamir 的分片数量不足、长度不一致或重复
+	ErrInvalidShares = errors.New("crab: invalid shares")
+	// ErrInvalidMnemonic 助记词包含未知单词或校验和错误
+	ErrInvalidMnemonic = errors.New("crab: invalid mnemonic")
+	// ErrInvalidProof Merkle 包含证明格式错误或与根不匹配
+	ErrInvalidProof = errors.New("crab: invalid merkle proof")
+	// ErrInvalidOTP 一次性密码不正确或已过期
+	ErrInvalidOTP = errors.New("crab: invalid one-time password")
+	// ErrOTPReplayed 一次性密码已经使用过
+	ErrOTPReplayed = errors.New("crab: one-time password already used")
+	// ErrInvalidID UUID、ULID 等 ID 格式错误
+	ErrInvalidID = errors.New("crab: invalid id")
+	// ErrClockBackwards 系统时钟回拨超过允许的范围
+	ErrClockBackwards = errors.New("crab: clock moved backwards")
+	// ErrInvalidSignature 签名格式错误或不匹配
+	ErrInvalidSignature = errors.New("crab: invalid signature")
+	// ErrSignatureExpired 签名已过期或时间戳超出允许的偏差
+	ErrSignatureExpired = errors.New("crab: signature expired")
+	// ErrInvalidEncoding base64、base58 等编码的输入不合法
+	ErrInvalidEncoding = errors.New("crab: invalid encoding")
+	// ErrTokenExpired 加密 token 或 cookie 已过期
+	ErrTokenExpired = errors.New("crab: token expired")
+	// ErrBodyTooLarge 请求 body 超过允许的大小
+	ErrBodyTooLarge = errors.New("crab: request body too large")
+)
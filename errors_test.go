@@ -0,0 +1,105 @@
+package crab
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/serialt/crab/internal"
+)
+
+func TestArgon2Errors(t *testing.T) {
+	assert := internal.NewAssert(t, "TestArgon2Errors")
+
+	hash := `argon2$4$32768$4$32$/WN2BY5NDzVlHYgw3pqahA==$oLGdDy23gAgbQXmphVVPG0Uax+XbfeUfH/TCpQbEHfc=`
+	_, err := Argon2CompareHashWithPassword(hash, "wrong")
+	assert.Equal(true, errors.Is(err, ErrPasswordMismatch))
+
+	_, err = Argon2CompareHashWithPassword("badHash", "wrong")
+	assert.Equal(true, errors.Is(err, ErrInvalidHashFormat))
+
+	_, err = Argon2CompareHashWithPassword(`argon2$x$32768$4$32$c2FsdA==$a2V5`, "wrong")
+	assert.Equal(true, errors.Is(err, ErrInvalidHashFormat))
+
+	_, err = Argon2CompareHashWithPassword(`scrypt$4$32768$4$32$c2FsdA==$a2V5`, "wrong")
+	assert.Equal(true, errors.Is(err, ErrUnsupportedAlgorithm))
+
+	_, err = Argon2GenerateSaltedHashWithType("Password1", "bcrypt")
+	assert.Equal(true, errors.Is(err, ErrUnsupportedAlgorithm))
+
+	_, err = Argon2GenerateSaltedHash("")
+	assert.Equal(true, errors.Is(err, ErrEmptyInput))
+}
+
+func TestAESErrors(t *testing.T) {
+	assert := internal.NewAssert(t, "TestAESErrors")
+
+	key := GenerateKey(32)
+	_, err := AESEncryptCBC([]byte("hello"), []byte("short"))
+	assert.Equal(true, errors.Is(err, ErrInvalidKeySize))
+
+	_, err = AESDecryptCBC([]byte("short"), key)
+	assert.Equal(true, errors.Is(err, ErrInvalidCiphertext))
+
+	_, err = pkcs7UnPadding([]byte{1, 2, 3, 0}, 16)
+	assert.Equal(true, errors.Is(err, ErrInvalidPadding))
+	_, err = pkcs7UnPadding([]byte{1, 2, 2, 3}, 16)
+	assert.Equal(true, errors.Is(err, ErrInvalidPadding))
+
+	data, err := AESEncryptGCM([]byte("hello"), key)
+	assert.IsNil(err)
+	data[len(data)-1] ^= 1
+	_, err = AESDecryptGCM(data, key)
+	assert.Equal(true, errors.Is(err, ErrAuthenticationFailed))
+
+	_, err = AESDecryptGCM(data[:4], key)
+	assert.Equal(true, errors.Is(err, ErrInvalidCiphertext))
+
+	_, err = AESDecryptGCMBase64("!!!", string(key))
+	assert.Equal(true, errors.Is(err, ErrInvalidCiphertext))
+}
+
+func TestChacha20Errors(t *testing.T) {
+	assert := internal.NewAssert(t, "TestChacha20Errors")
+
+	_, err := Chacha20AEADEncrypt([]byte("hello"), GenerateKey(16))
+	assert.Equal(true, errors.Is(err, ErrInvalidKeySize))
+
+	key := GenerateKey(32)
+	data, err := Chacha20AEADEncrypt([]byte("hello"), key)
+	assert.IsNil(err)
+	_, err = Chacha20AEADDecrypt(data, GenerateKey(32))
+	assert.Equal(true, errors.Is(err, ErrAuthenticationFailed))
+}
+
+func TestRSAErrors(t *testing.T) {
+	assert := internal.NewAssert(t, "TestRSAErrors")
+
+	_, err := RSAEncryptOAEP([]byte("hello"), []byte("not a pem"))
+	assert.Equal(true, errors.Is(err, ErrInvalidKey))
+
+	pass := []byte("sugar")
+	priKey, pubKey, err := GenerateRSAKeyWithPwd(pass, 2048)
+	assert.IsNil(err)
+	data, err := RSAEncryptOAEP([]byte("hello"), pubKey)
+	assert.IsNil(err)
+
+	_, err = RSADecryptOAEPPwd(data, priKey, []byte("wrong"))
+	assert.Equal(true, errors.Is(err, ErrPasswordMismatch))
+
+	data[0] ^= 1
+	_, err = RSADecryptOAEPPwd(data, priKey, pass)
+	assert.Equal(true, errors.Is(err, ErrAuthenticationFailed))
+}
+
+func TestShamirErrors(t *testing.T) {
+	assert := internal.NewAssert(t, "TestShamirErrors")
+
+	_, err := ShamirSplit([]byte("test"), 2, 3)
+	assert.Equal(true, errors.Is(err, ErrInvalidThreshold))
+
+	_, err = ShamirSplit(nil, 3, 2)
+	assert.Equal(true, errors.Is(err, ErrEmptyInput))
+
+	_, err = ShamirCombine([][]byte{[]byte("foo"), []byte("foo")})
+	assert.Equal(true, errors.Is(err, ErrInvalidShares))
+}
@@ -0,0 +1,163 @@
+package crab
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha3"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// HashAlgorithm 摘要算法
+type HashAlgorithm string
+
+const (
+	HashMD5     HashAlgorithm = "md5"
+	HashSHA1    HashAlgorithm = "sha1"
+	HashSHA256  HashAlgorithm = "sha256"
+	HashSHA512  HashAlgorithm = "sha512"
+	HashSHA3256 HashAlgorithm = "sha3-256"
+	// HashBLAKE2b BLAKE2b-512
+	HashBLAKE2b HashAlgorithm = "blake2b"
+	// HashCRC32 IEEE 多项式的 CRC-32
+	HashCRC32 HashAlgorithm = "crc32"
+)
+
+// defaultHashBufferSize 每次读取的字节数
+const defaultHashBufferSize = 64 * 1024
+
+// NewHash 返回指定算法的 hash.Hash
+func NewHash(alg HashAlgorithm) (hash.Hash, error) {
+	switch alg {
+	case HashMD5:
+		return md5.New(), nil
+	case HashSHA1:
+		return sha1.New(), nil
+	case HashSHA256:
+		return sha256.New(), nil
+	case HashSHA512:
+		return sha512.New(), nil
+	case HashSHA3256:
+		return sha3.New256(), nil
+	case HashBLAKE2b:
+		return blake2b.New512(nil)
+	case HashCRC32:
+		return crc32.NewIEEE(), nil
+	}
+	return nil, fmt.Errorf("%w: hash %q", ErrUnsupportedAlgorithm, alg)
+}
+
+// HashOpt HashReader 和 HashFile 的参数
+type HashOpt struct {
+	// Algorithms 要计算的算法, 为空时只计算 sha256
+	Algorithms []HashAlgorithm
+	// Progress 每读完一块调用一次, read 为已读取的字节数, total 未知时为 -1
+	Progress func(read, total int64)
+	// BufferSize 每次读取的字节数, 默认 64 KiB
+	BufferSize int
+}
+
+// HashSums 每个算法的摘要
+type HashSums map[HashAlgorithm][]byte
+
+// Hex 返回指定算法摘要的十六进制字符串, 没有计算该算法时返回空字符串
+func (s HashSums) Hex(alg HashAlgorithm) string {
+	if sum, ok := s[alg]; ok {
+		return hex.EncodeToString(sum)
+	}
+	return ""
+}
+
+// HexMap 返回所有摘要的十六进制字符串
+func (s HashSums) HexMap() map[HashAlgorithm]string {
+	out := make(map[HashAlgorithm]string, len(s))
+	for alg, sum := range s {
+		out[alg] = hex.EncodeToString(sum)
+	}
+	return out
+}
+
+// HashReader 读取一遍 r 同时计算多个摘要, ctx 取消时返回 ctx.Err()
+func HashReader(ctx context.Context, r io.Reader, opt HashOpt) (HashSums, error) {
+	return hashReader(ctx, r, -1, opt)
+}
+
+// HashFile 读取一遍文件同时计算多个摘要, Progress 的 total 为文件大小
+func HashFile(ctx context.Context, path string, opt HashOpt) (HashSums, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", path)
+	}
+	return hashReader(ctx, f, info.Size(), opt)
+}
+
+func hashReader(ctx context.Context, r io.Reader, total int64, opt HashOpt) (HashSums, error) {
+	algs := opt.Algorithms
+	if len(algs) == 0 {
+		algs = []HashAlgorithm{HashSHA256}
+	}
+	hashes := make(map[HashAlgorithm]hash.Hash, len(algs))
+	writers := make([]io.Writer, 0, len(algs))
+	for _, alg := range algs {
+		if _, ok := hashes[alg]; ok {
+			continue
+		}
+		h, err := NewHash(alg)
+		if err != nil {
+			return nil, err
+		}
+		hashes[alg] = h
+		writers = append(writers, h)
+	}
+	w := io.MultiWriter(writers...)
+
+	size := opt.BufferSize
+	if size <= 0 {
+		size = defaultHashBufferSize
+	}
+	buf := make([]byte, size)
+	var read int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		n, err := r.Read(buf)
+		if n > 0 {
+			w.Write(buf[:n])
+			read += int64(n)
+			if opt.Progress != nil {
+				opt.Progress(read, total)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sums := make(HashSums, len(hashes))
+	for alg, h := range hashes {
+		sums[alg] = h.Sum(nil)
+	}
+	return sums, nil
+}
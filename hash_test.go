@@ -0,0 +1,91 @@
+package crab
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/serialt/crab/internal"
+)
+
+func TestHashReader(t *testing.T) {
+	assert := internal.NewAssert(t, "TestHashReader")
+
+	expected := map[HashAlgorithm]string{
+		HashMD5:     "5eb63bbbe01eeed093cb22bb8f5acdc3",
+		HashSHA1:    "2aae6c35c94fcfb415dbe95f408b9ce91ee846ed",
+		HashSHA256:  "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9",
+		HashSHA512:  "309ecc489c12d6eb4cc40f50c902f2b4d0ed77ee511a7c7a9bcd3ca86d4cd86f989dd35bc5ff499670da34255b45b0cfd830e81f605dcf7dc5542e93ae9cd76f",
+		HashSHA3256: "644bcc7e564373040999aac89e7622f3ca71fba1d972fd94a31c3bfbf24e3938",
+		HashBLAKE2b: "021ced8799296ceca557832ab941a50b4a11f83478cf141f51f933f653ab9fbcc05a037cddbed06e309bf334942c4e58cdf1a46e237911ccd7fcf9787cbc7fd0",
+		HashCRC32:   "0d4a1185",
+	}
+	var algs []HashAlgorithm
+	for alg := range expected {
+		algs = append(algs, alg)
+	}
+
+	var calls int
+	sums, err := HashReader(context.Background(), strings.NewReader("hello world"), HashOpt{
+		Algorithms: algs,
+		BufferSize: 4,
+		Progress: func(read, total int64) {
+			calls++
+			assert.Equal(int64(-1), total)
+		},
+	})
+	assert.IsNil(err)
+	assert.Equal(expected, sums.HexMap())
+	assert.Equal(3, calls)
+
+	// 默认 sha256
+	sums, err = HashReader(context.Background(), strings.NewReader("hello world"), HashOpt{})
+	assert.IsNil(err)
+	assert.Equal(1, len(sums))
+	assert.Equal(expected[HashSHA256], sums.Hex(HashSHA256))
+	assert.Equal("", sums.Hex(HashMD5))
+
+	_, err = HashReader(context.Background(), strings.NewReader(""), HashOpt{Algorithms: []HashAlgorithm{"md4"}})
+	assert.Equal(true, errors.Is(err, ErrUnsupportedAlgorithm))
+}
+
+func TestHashFile(t *testing.T) {
+	assert := internal.NewAssert(t, "TestHashFile")
+
+	sums, err := HashFile(context.Background(), "./basic.go", HashOpt{
+		Algorithms: []HashAlgorithm{HashMD5, HashSHA1, HashSHA256, HashSHA512},
+		Progress: func(read, total int64) {
+			assert.LessOrEqual(read, total)
+		},
+	})
+	assert.IsNil(err)
+
+	md5sum, _ := Md5File("./basic.go")
+	sha1sum, _ := Sha1File("./basic.go")
+	sha256sum, _ := Sha256File("./basic.go")
+	sha512sum, _ := Sha512File("./basic.go")
+	assert.Equal(md5sum, sums.Hex(HashMD5))
+	assert.Equal(sha1sum, sums.Hex(HashSHA1))
+	assert.Equal(sha256sum, sums.Hex(HashSHA256))
+	assert.Equal(sha512sum, sums.Hex(HashSHA512))
+
+	_, err = HashFile(context.Background(), "./testdata", HashOpt{})
+	assert.IsNotNil(err)
+}
+
+func TestHashReaderCancel(t *testing.T) {
+	assert := internal.NewAssert(t, "TestHashReaderCancel")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err := HashReader(ctx, bytes.NewReader(make([]byte, 1<<20)), HashOpt{
+		BufferSize: 1024,
+		Progress: func(read, total int64) {
+			if read >= 4096 {
+				cancel()
+			}
+		},
+	})
+	assert.Equal(true, errors.Is(err, context.Canceled))
+}
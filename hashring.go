@@ -0,0 +1,263 @@
+package crab
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// defaultRingReplicas 权重为 1 的节点在环上的虚拟节点数
+const defaultRingReplicas = 160
+
+// HashFunc 把 key 映射为 uint64, 用于一致性 hash
+type HashFunc func(data []byte) uint64
+
+// Md5Hash 取 md5 的前 8 个字节, 是 HashRing 和 Rendezvous 的默认 hash
+func Md5Hash(data []byte) uint64 {
+	sum := md5.Sum(data)
+	return binary.BigEndian.Uint64(sum[:8])
+}
+
+// HashRingOpt HashRing 配置
+type HashRingOpt struct {
+	// Replicas 权重为 1 的节点的虚拟节点数, 默认 160
+	Replicas int
+	// Hash hash 函数, 默认 Md5Hash
+	Hash HashFunc
+}
+
+// HashRing 带虚拟节点和权重的一致性 hash 环
+// 增删节点时只有约 1/n 的 key 会迁移, 可以并发读取
+type HashRing struct {
+	hash     HashFunc
+	replicas int
+
+	mu      sync.RWMutex
+	weights map[string]int
+	points  []ringPoint
+}
+
+type ringPoint struct {
+	hash uint64
+	node string
+}
+
+// NewHashRing 创建一致性 hash 环, 可以同时加入权重为 1 的 nodes
+func NewHashRing(opt HashRingOpt, nodes ...string) *HashRing {
+	r := &HashRing{
+		hash:     opt.Hash,
+		replicas: opt.Replicas,
+		weights:  map[string]int{},
+	}
+	if r.hash == nil {
+		r.hash = Md5Hash
+	}
+	if r.replicas <= 0 {
+		r.replicas = defaultRingReplicas
+	}
+	for _, node := range nodes {
+		r.weights[node] = 1
+	}
+	r.rebuild()
+	return r
+}
+
+// Add 加入节点, 虚拟节点数为 Replicas * weight; 节点已存在时更新权重
+func (r *HashRing) Add(node string, weight int) error {
+	if weight <= 0 {
+		return fmt.Errorf("invalid weight %d for node %s", weight, node)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.weights[node] = weight
+	r.rebuild()
+	return nil
+}
+
+// Remove 移除节点, 节点不存在时什么也不做
+func (r *HashRing) Remove(node string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.weights[node]; !ok {
+		return
+	}
+	delete(r.weights, node)
+	r.rebuild()
+}
+
+// Nodes 返回所有节点, 按名称排序
+func (r *HashRing) Nodes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return sortedNodes(r.weights)
+}
+
+// Get 返回 key 所在的节点, 环为空时返回空字符串
+func (r *HashRing) Get(key string) string {
+	nodes := r.GetN(key, 1)
+	if len(nodes) == 0 {
+		return ""
+	}
+	return nodes[0]
+}
+
+// GetN 返回 key 的 n 个副本所在的不同节点, 第一个与 Get 相同
+// 节点数不足 n 时返回所有节点
+func (r *HashRing) GetN(key string, n int) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	n = min(n, len(r.weights))
+	if n <= 0 {
+		return nil
+	}
+	h := r.hash([]byte(key))
+	start := sort.Search(len(r.points), func(i int) bool {
+		return r.points[i].hash >= h
+	})
+
+	nodes := make([]string, 0, n)
+	seen := make(map[string]bool, n)
+	for i := 0; len(nodes) < n; i++ {
+		p := r.points[(start+i)%len(r.points)]
+		if !seen[p.node] {
+			seen[p.node] = true
+			nodes = append(nodes, p.node)
+		}
+	}
+	return nodes
+}
+
+// rebuild 重新生成环上的虚拟节点, 调用方需持有写锁
+func (r *HashRing) rebuild() {
+	total := 0
+	for _, weight := range r.weights {
+		total += weight * r.replicas
+	}
+	points := make([]ringPoint, 0, total)
+	for node, weight := range r.weights {
+		for i := 0; i < weight*r.replicas; i++ {
+			points = append(points, ringPoint{
+				hash: r.hash([]byte(node + "#" + strconv.Itoa(i))),
+				node: node,
+			})
+		}
+	}
+	sort.Slice(points, func(i, j int) bool {
+		if points[i].hash != points[j].hash {
+			return points[i].hash < points[j].hash
+		}
+		return points[i].node < points[j].node
+	})
+	r.points = points
+}
+
+// Rendezvous 带权重的最高随机权重 (HRW) hash
+// 不需要虚拟节点, 增删节点时只有该节点上的 key 会迁移, 适合节点较少的场景; 可以并发读取
+type Rendezvous struct {
+	hash HashFunc
+
+	mu      sync.RWMutex
+	weights map[string]int
+}
+
+// NewRendezvous 创建 Rendezvous, hash 为 nil 时使用 Md5Hash, 可以同时加入权重为 1 的 nodes
+func NewRendezvous(hash HashFunc, nodes ...string) *Rendezvous {
+	if hash == nil {
+		hash = Md5Hash
+	}
+	r := &Rendezvous{hash: hash, weights: map[string]int{}}
+	for _, node := range nodes {
+		r.weights[node] = 1
+	}
+	return r
+}
+
+// Add 加入节点, 节点已存在时更新权重
+func (r *Rendezvous) Add(node string, weight int) error {
+	if weight <= 0 {
+		return fmt.Errorf("invalid weight %d for node %s", weight, node)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.weights[node] = weight
+	return nil
+}
+
+// Remove 移除节点
+func (r *Rendezvous) Remove(node string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.weights, node)
+}
+
+// Nodes 返回所有节点, 按名称排序
+func (r *Rendezvous) Nodes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return sortedNodes(r.weights)
+}
+
+// Get 返回 key 所在的节点, 没有节点时返回空字符串
+func (r *Rendezvous) Get(key string) string {
+	nodes := r.GetN(key, 1)
+	if len(nodes) == 0 {
+		return ""
+	}
+	return nodes[0]
+}
+
+// GetN 返回得分最高的 n 个节点
+func (r *Rendezvous) GetN(key string, n int) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	n = min(n, len(r.weights))
+	if n <= 0 {
+		return nil
+	}
+	nodes := sortedNodes(r.weights)
+	scores := make(map[string]float64, len(nodes))
+	for _, node := range nodes {
+		// 把 hash 映射到 (0, 1), 得分 -w/ln(u) 使节点被选中的概率与权重成正比
+		u := (float64(r.hash([]byte(node+"#"+key))>>11) + 0.5) / (1 << 53)
+		scores[node] = -float64(r.weights[node]) / math.Log(u)
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return scores[nodes[i]] > scores[nodes[j]]
+	})
+	return nodes[:n]
+}
+
+// JumpHash Google 的 jump consistent hash, 返回 key 所在的桶 [0, buckets)
+// 不占用内存, 但只能在末尾增删桶, 适合按编号分片的存储; buckets <= 0 时返回 -1
+func JumpHash(key uint64, buckets int) int {
+	if buckets <= 0 {
+		return -1
+	}
+	var b, j int64 = -1, 0
+	for j < int64(buckets) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return int(b)
+}
+
+// JumpHashString 用 Md5Hash 计算 key 后调用 JumpHash
+func JumpHashString(key string, buckets int) int {
+	return JumpHash(Md5Hash([]byte(key)), buckets)
+}
+
+func sortedNodes(weights map[string]int) []string {
+	nodes := make([]string, 0, len(weights))
+	for node := range weights {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	return nodes
+}
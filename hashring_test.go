@@ -0,0 +1,148 @@
+package crab
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/serialt/crab/internal"
+)
+
+func TestHashRing(t *testing.T) {
+	assert := internal.NewAssert(t, "TestHashRing")
+
+	ring := NewHashRing(HashRingOpt{})
+	assert.Equal("", ring.Get("key"))
+	assert.Equal(0, len(ring.GetN("key", 3)))
+
+	ring = NewHashRing(HashRingOpt{}, "node-a", "node-b", "node-c")
+	assert.Equal([]string{"node-a", "node-b", "node-c"}, ring.Nodes())
+
+	const keys = 10000
+	before := map[string]string{}
+	counts := map[string]int{}
+	for i := 0; i < keys; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		before[key] = ring.Get(key)
+		counts[before[key]]++
+	}
+	for _, count := range counts {
+		assert.Less(keys/5, count)
+	}
+
+	// 加入节点后只有新节点上的 key 会迁移
+	assert.IsNil(ring.Add("node-d", 1))
+	moved := 0
+	for key, node := range before {
+		if now := ring.Get(key); now != node {
+			assert.Equal("node-d", now)
+			moved++
+		}
+	}
+	assert.Less(keys/8, moved)
+	assert.Greater(keys/2, moved)
+
+	ring.Remove("node-d")
+	for key, node := range before {
+		assert.Equal(node, ring.Get(key))
+	}
+
+	replicas := ring.GetN("key-1", 2)
+	assert.Equal(2, len(replicas))
+	assert.Equal(ring.Get("key-1"), replicas[0])
+	assert.NotEqual(replicas[0], replicas[1])
+	assert.Equal(3, len(ring.GetN("key-1", 5)))
+
+	assert.IsNotNil(ring.Add("node-e", 0))
+}
+
+func TestHashRingWeight(t *testing.T) {
+	assert := internal.NewAssert(t, "TestHashRingWeight")
+
+	calls := 0
+	countingHash := func(data []byte) uint64 {
+		calls++
+		return Md5Hash(data)
+	}
+	ring := NewHashRing(HashRingOpt{Replicas: 100, Hash: countingHash})
+	assert.IsNil(ring.Add("small", 1))
+	assert.IsNil(ring.Add("large", 3))
+
+	counts := map[string]int{}
+	for i := 0; i < 10000; i++ {
+		counts[ring.Get(fmt.Sprintf("key-%d", i))]++
+	}
+	assert.Less(counts["small"]*2, counts["large"])
+	assert.Equal(100+400+10000, calls)
+}
+
+func TestHashRingConcurrent(t *testing.T) {
+	ring := NewHashRing(HashRingOpt{}, "node-a", "node-b")
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				ring.GetN(fmt.Sprintf("key-%d-%d", i, j), 2)
+				if j%50 == 0 {
+					_ = ring.Add(fmt.Sprintf("node-%d", i), 1)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestRendezvous(t *testing.T) {
+	assert := internal.NewAssert(t, "TestRendezvous")
+
+	r := NewRendezvous(nil, "node-a", "node-b", "node-c")
+	before := map[string]string{}
+	for i := 0; i < 3000; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		before[key] = r.Get(key)
+	}
+
+	r.Remove("node-b")
+	for key, node := range before {
+		if node != "node-b" {
+			assert.Equal(node, r.Get(key))
+		}
+	}
+
+	assert.IsNil(r.Add("node-b", 1))
+	nodes := r.GetN("key-1", 3)
+	assert.Equal(3, len(nodes))
+	assert.Equal(r.Get("key-1"), nodes[0])
+
+	assert.IsNil(r.Add("node-a", 4))
+	counts := map[string]int{}
+	for key := range before {
+		counts[r.Get(key)]++
+	}
+	assert.Less(counts["node-b"]*2, counts["node-a"])
+
+	assert.Equal("", NewRendezvous(nil).Get("key"))
+}
+
+func TestJumpHash(t *testing.T) {
+	assert := internal.NewAssert(t, "TestJumpHash")
+
+	assert.Equal(-1, JumpHash(1, 0))
+	assert.Equal(0, JumpHash(12345, 1))
+
+	moved := 0
+	for i := 0; i < 10000; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		b10 := JumpHashString(key, 10)
+		assert.Greater(10, b10)
+		b11 := JumpHashString(key, 11)
+		if b10 != b11 {
+			assert.Equal(10, b11)
+			moved++
+		}
+	}
+	assert.Less(500, moved)
+	assert.Greater(1500, moved)
+}
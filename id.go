@@ -0,0 +1,367 @@
+package crab
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// UUID RFC 9562 UUID
+type UUID [16]byte
+
+var (
+	uuidMu     sync.Mutex
+	uuidLastMs int64
+	uuidSeq    uint16
+)
+
+// NewUUIDv4 生成随机 UUID
+func NewUUIDv4() (UUID, error) {
+	var u UUID
+	if err := randRead(u[:]); err != nil {
+		return u, err
+	}
+	u.setVersion(4)
+	return u, nil
+}
+
+// NewUUIDv7 生成以毫秒时间戳开头的 UUID, 可以按字节或字符串排序
+// 同一毫秒内用 12 位 rand_a 作为递增计数器, 保证同一进程内严格递增
+func NewUUIDv7() (UUID, error) {
+	var u UUID
+	if err := randRead(u[6:]); err != nil {
+		return u, err
+	}
+
+	uuidMu.Lock()
+	ms := time.Now().UnixMilli()
+	if ms > uuidLastMs {
+		// 计数器从随机值开始, 最高位清零留出递增空间
+		uuidLastMs, uuidSeq = ms, binary.BigEndian.Uint16(u[6:8])&0x07ff
+	} else if uuidSeq++; uuidSeq > 0x0fff {
+		uuidLastMs, uuidSeq = uuidLastMs+1, 0
+	}
+	ms, seq := uuidLastMs, uuidSeq
+	uuidMu.Unlock()
+
+	var ts [8]byte
+	binary.BigEndian.PutUint64(ts[:], uint64(ms))
+	copy(u[:6], ts[2:])
+	binary.BigEndian.PutUint16(u[6:8], seq)
+	u.setVersion(7)
+	return u, nil
+}
+
+// ParseUUID 解析 UUID, 支持 xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx、32 位 hex、{...} 和 urn:uuid: 格式
+func ParseUUID(s string) (UUID, error) {
+	var u UUID
+	raw := strings.TrimPrefix(strings.ToLower(s), "urn:uuid:")
+	if strings.HasPrefix(raw, "{") && strings.HasSuffix(raw, "}") {
+		raw = raw[1 : len(raw)-1]
+	}
+	if len(raw) == 36 {
+		if raw[8] != '-' || raw[13] != '-' || raw[18] != '-' || raw[23] != '-' {
+			return u, fmt.Errorf("%w: uuid %q", ErrInvalidID, s)
+		}
+		raw = raw[:8] + raw[9:13] + raw[14:18] + raw[19:23] + raw[24:]
+	}
+	if len(raw) != 32 {
+		return u, fmt.Errorf("%w: uuid %q", ErrInvalidID, s)
+	}
+	if _, err := hex.Decode(u[:], []byte(raw)); err != nil {
+		return u, fmt.Errorf("%w: uuid %q", ErrInvalidID, s)
+	}
+	return u, nil
+}
+
+// String 返回小写的 xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx 格式
+func (u UUID) String() string {
+	var b [36]byte
+	hex.Encode(b[:8], u[:4])
+	b[8] = '-'
+	hex.Encode(b[9:13], u[4:6])
+	b[13] = '-'
+	hex.Encode(b[14:18], u[6:8])
+	b[18] = '-'
+	hex.Encode(b[19:23], u[8:10])
+	b[23] = '-'
+	hex.Encode(b[24:], u[10:])
+	return string(b[:])
+}
+
+// Version 返回 UUID 版本
+func (u UUID) Version() int {
+	return int(u[6] >> 4)
+}
+
+// Time 返回 v7 UUID 中的时间, 其他版本返回零值
+func (u UUID) Time() time.Time {
+	if u.Version() != 7 {
+		return time.Time{}
+	}
+	var ts [8]byte
+	copy(ts[2:], u[:6])
+	return time.UnixMilli(int64(binary.BigEndian.Uint64(ts[:])))
+}
+
+func (u *UUID) setVersion(v byte) {
+	u[6] = u[6]&0x0f | v<<4
+	// RFC 9562 variant 10xx
+	u[8] = u[8]&0x3f | 0x80
+}
+
+// ULID 48 位毫秒时间戳 + 80 位随机数, 字符串为 26 位 Crockford base32
+type ULID [16]byte
+
+const ulidAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+var (
+	ulidMu   sync.Mutex
+	ulidLast ULID
+)
+
+// NewULID 生成 ULID, 同一毫秒内随机部分加 1, 保证同一进程内严格递增
+// 同一毫秒内生成超过 2^80 个时返回错误
+func NewULID() (ULID, error) {
+	var u ULID
+	ms := uint64(time.Now().UnixMilli())
+
+	ulidMu.Lock()
+	defer ulidMu.Unlock()
+	if ms <= ulidLast.timestamp() {
+		u = ulidLast
+		for i := len(u) - 1; ; i-- {
+			if i < 6 {
+				return ULID{}, fmt.Errorf("ulid random part overflow")
+			}
+			if u[i]++; u[i] != 0 {
+				break
+			}
+		}
+	} else {
+		if err := randRead(u[6:]); err != nil {
+			return u, err
+		}
+		var ts [8]byte
+		binary.BigEndian.PutUint64(ts[:], ms)
+		copy(u[:6], ts[2:])
+	}
+	ulidLast = u
+	return u, nil
+}
+
+// ParseULID 解析 26 位 ULID 字符串, 不区分大小写
+func ParseULID(s string) (ULID, error) {
+	var u ULID
+	if len(s) != 26 || ulidDecode(s[0]) > 7 {
+		return u, fmt.Errorf("%w: ulid %q", ErrInvalidID, s)
+	}
+	// 26 * 5 = 130 位, 第一位字符只有低 3 位有效
+	var acc uint64
+	bits, pos := 0, 0
+	for i := 0; i < len(s); i++ {
+		v := ulidDecode(s[i])
+		if v == 0xff {
+			return ULID{}, fmt.Errorf("%w: ulid %q", ErrInvalidID, s)
+		}
+		if i == 0 {
+			acc, bits = uint64(v), 3
+			continue
+		}
+		acc = acc<<5 | uint64(v)
+		bits += 5
+		for bits >= 8 {
+			bits -= 8
+			u[pos] = byte(acc >> bits)
+			pos++
+		}
+	}
+	return u, nil
+}
+
+// String 返回 26 位 Crockford base32 编码
+func (u ULID) String() string {
+	var b [26]byte
+	var acc uint64
+	bits, pos := 2, 0 // 128 位补齐到 130 位, 高位补 2 个 0
+	for _, c := range u {
+		acc = acc<<8 | uint64(c)
+		bits += 8
+		for bits >= 5 {
+			bits -= 5
+			b[pos] = ulidAlphabet[acc>>bits&0x1f]
+			pos++
+		}
+	}
+	return string(b[:])
+}
+
+// Time 返回 ULID 中的时间
+func (u ULID) Time() time.Time {
+	return time.UnixMilli(int64(u.timestamp()))
+}
+
+func (u ULID) timestamp() uint64 {
+	var ts [8]byte
+	copy(ts[2:], u[:6])
+	return binary.BigEndian.Uint64(ts[:])
+}
+
+func ulidDecode(c byte) byte {
+	if c >= 'a' && c <= 'z' {
+		c -= 'a' - 'A'
+	}
+	switch c {
+	case 'O':
+		c = '0'
+	case 'I', 'L':
+		c = '1'
+	}
+	if i := strings.IndexByte(ulidAlphabet, c); i >= 0 {
+		return byte(i)
+	}
+	return 0xff
+}
+
+const (
+	snowflakeWorkerBits   = 10
+	snowflakeSequenceBits = 12
+	// SnowflakeMaxWorkerID 最大的 worker ID
+	SnowflakeMaxWorkerID = 1<<snowflakeWorkerBits - 1
+	snowflakeMaxSequence = 1<<snowflakeSequenceBits - 1
+)
+
+// defaultSnowflakeEpoch 默认起始时间 2020-01-01 UTC
+var defaultSnowflakeEpoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// SnowflakeOpt Snowflake 配置
+type SnowflakeOpt struct {
+	// Epoch 起始时间, 默认 2020-01-01 UTC, 41 位毫秒时间戳约可使用 69 年
+	Epoch time.Time
+	// WorkerID 0 到 1023, 同时运行的生成器必须不同
+	WorkerID int64
+	// MaxClockBackward 时钟回拨不超过这个时间时等待时钟追上, 超过时返回 ErrClockBackwards, 默认 0 即不等待
+	MaxClockBackward time.Duration
+}
+
+// Snowflake 64 位递增 ID 生成器: 1 位符号 | 41 位毫秒时间戳 | 10 位 worker ID | 12 位序号
+// 可以并发使用, 每毫秒最多生成 4096 个, 超过时等待下一毫秒
+type Snowflake struct {
+	epoch       int64
+	workerID    int64
+	maxBackward time.Duration
+	now         func() time.Time
+
+	mu       sync.Mutex
+	lastMs   int64
+	sequence int64
+}
+
+// NewSnowflake 创建 Snowflake 生成器
+func NewSnowflake(opt SnowflakeOpt) (*Snowflake, error) {
+	if opt.WorkerID < 0 || opt.WorkerID > SnowflakeMaxWorkerID {
+		return nil, fmt.Errorf("invalid snowflake worker id %d", opt.WorkerID)
+	}
+	epoch := opt.Epoch
+	if epoch.IsZero() {
+		epoch = defaultSnowflakeEpoch
+	}
+	return &Snowflake{
+		epoch:       epoch.UnixMilli(),
+		workerID:    opt.WorkerID,
+		maxBackward: opt.MaxClockBackward,
+		now:         time.Now,
+	}, nil
+}
+
+// Next 生成下一个 ID
+func (s *Snowflake) Next() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ms := s.now().UnixMilli() - s.epoch
+	if ms < 0 {
+		return 0, fmt.Errorf("snowflake epoch is in the future")
+	}
+	if ms < s.lastMs {
+		backward := time.Duration(s.lastMs-ms) * time.Millisecond
+		if backward > s.maxBackward {
+			return 0, fmt.Errorf("%w: %s", ErrClockBackwards, backward)
+		}
+		ms = s.waitUntil(s.lastMs)
+	}
+	if ms == s.lastMs {
+		s.sequence = (s.sequence + 1) & snowflakeMaxSequence
+		if s.sequence == 0 {
+			ms = s.waitUntil(s.lastMs + 1)
+		}
+	} else {
+		s.sequence = 0
+	}
+	if ms >= 1<<41 {
+		return 0, fmt.Errorf("snowflake timestamp out of range, check the epoch")
+	}
+	s.lastMs = ms
+	return ms<<(snowflakeWorkerBits+snowflakeSequenceBits) | s.workerID<<snowflakeSequenceBits | s.sequence, nil
+}
+
+// Decompose 拆分 ID, 返回生成时间、worker ID 和序号
+func (s *Snowflake) Decompose(id int64) (t time.Time, workerID, sequence int64) {
+	ms := id >> (snowflakeWorkerBits + snowflakeSequenceBits)
+	workerID = id >> snowflakeSequenceBits & SnowflakeMaxWorkerID
+	sequence = id & snowflakeMaxSequence
+	return time.UnixMilli(s.epoch + ms), workerID, sequence
+}
+
+// waitUntil 等待到相对 epoch 的毫秒数 ms, 返回当前毫秒数
+func (s *Snowflake) waitUntil(ms int64) int64 {
+	now := s.now().UnixMilli() - s.epoch
+	for now < ms {
+		time.Sleep(time.Duration(ms-now) * time.Millisecond)
+		now = s.now().UnixMilli() - s.epoch
+	}
+	return now
+}
+
+const (
+	// NanoIDAlphabet NanoID 默认的 URL 安全字符集
+	NanoIDAlphabet = "_-0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	// defaultNanoIDSize 默认长度, 碰撞概率与 UUID v4 相当
+	defaultNanoIDSize = 21
+)
+
+// NanoID 生成 URL 安全的随机 ID, size <= 0 时为 21 位
+func NanoID(size int) (string, error) {
+	return NanoIDWithAlphabet(NanoIDAlphabet, size)
+}
+
+// NanoIDWithAlphabet 用自定义字符集生成随机 ID, 字符集需要 2 到 256 个不重复的字符
+func NanoIDWithAlphabet(alphabet string, size int) (string, error) {
+	chars := []rune(alphabet)
+	if len(chars) < 2 || len(chars) > 256 {
+		return "", fmt.Errorf("invalid nanoid alphabet length %d", len(chars))
+	}
+	seen := map[rune]bool{}
+	for _, c := range chars {
+		if seen[c] {
+			return "", fmt.Errorf("duplicate character %q in nanoid alphabet", c)
+		}
+		seen[c] = true
+	}
+	if size <= 0 {
+		size = defaultNanoIDSize
+	}
+
+	b := make([]rune, size)
+	for i := range b {
+		idx, err := randIntn(len(chars))
+		if err != nil {
+			return "", err
+		}
+		b[i] = chars[idx]
+	}
+	return string(b), nil
+}
@@ -0,0 +1,187 @@
+package crab
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/serialt/crab/internal"
+)
+
+func TestUUID(t *testing.T) {
+	assert := internal.NewAssert(t, "TestUUID")
+
+	u, err := NewUUIDv4()
+	assert.IsNil(err)
+	assert.Equal(4, u.Version())
+	assert.Equal(byte(0x80), u[8]&0xc0)
+	assert.Equal(true, u.Time().IsZero())
+
+	parsed, err := ParseUUID(u.String())
+	assert.IsNil(err)
+	assert.Equal(u, parsed)
+
+	for _, s := range []string{
+		"f81d4fae-7dec-11d0-a765-00a0c91e6bf6",
+		"F81D4FAE-7DEC-11D0-A765-00A0C91E6BF6",
+		"{f81d4fae-7dec-11d0-a765-00a0c91e6bf6}",
+		"urn:uuid:f81d4fae-7dec-11d0-a765-00a0c91e6bf6",
+		"f81d4fae7dec11d0a76500a0c91e6bf6",
+	} {
+		u, err := ParseUUID(s)
+		assert.IsNil(err)
+		assert.Equal("f81d4fae-7dec-11d0-a765-00a0c91e6bf6", u.String())
+		assert.Equal(1, u.Version())
+	}
+	for _, s := range []string{"", "f81d4fae-7dec-11d0-a765_00a0c91e6bf6", "g81d4fae7dec11d0a76500a0c91e6bf6", "f81d4fae7dec11d0a76500a0c91e6b"} {
+		_, err := ParseUUID(s)
+		assert.Equal(true, errors.Is(err, ErrInvalidID))
+	}
+}
+
+func TestUUIDv7(t *testing.T) {
+	assert := internal.NewAssert(t, "TestUUIDv7")
+
+	before := time.Now().Truncate(time.Millisecond)
+	var ids []string
+	for i := 0; i < 5000; i++ {
+		u, err := NewUUIDv7()
+		assert.IsNil(err)
+		assert.Equal(7, u.Version())
+		ids = append(ids, u.String())
+	}
+	assert.Equal(true, sort.StringsAreSorted(ids))
+	for i := 1; i < len(ids); i++ {
+		assert.NotEqual(ids[i-1], ids[i])
+	}
+
+	u, _ := ParseUUID(ids[0])
+	assert.Equal(false, u.Time().Before(before))
+}
+
+func TestULID(t *testing.T) {
+	assert := internal.NewAssert(t, "TestULID")
+
+	var ids []string
+	for i := 0; i < 5000; i++ {
+		u, err := NewULID()
+		assert.IsNil(err)
+		ids = append(ids, u.String())
+	}
+	assert.Equal(true, sort.StringsAreSorted(ids))
+	for i := 1; i < len(ids); i++ {
+		assert.NotEqual(ids[i-1], ids[i])
+	}
+
+	// ULID 规范中的示例
+	u, err := ParseULID("01ARZ3NDEKTSV4RRFFQ69G5FAV")
+	assert.IsNil(err)
+	assert.Equal("01ARZ3NDEKTSV4RRFFQ69G5FAV", u.String())
+	assert.Equal(int64(1469922850259), u.Time().UnixMilli())
+
+	lower, err := ParseULID(strings.ToLower("01ARZ3NDEKTSV4RRFFQ69G5FAV"))
+	assert.IsNil(err)
+	assert.Equal(u, lower)
+
+	max, err := ParseULID("7ZZZZZZZZZZZZZZZZZZZZZZZZZ")
+	assert.IsNil(err)
+	assert.Equal(ULID{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, max)
+
+	for _, s := range []string{"", "8ZZZZZZZZZZZZZZZZZZZZZZZZZ", "01ARZ3NDEKTSV4RRFFQ69G5FA!", "01ARZ3NDEKTSV4RRFFQ69G5FAU"} {
+		_, err := ParseULID(s)
+		assert.Equal(true, errors.Is(err, ErrInvalidID))
+	}
+}
+
+func TestSnowflake(t *testing.T) {
+	assert := internal.NewAssert(t, "TestSnowflake")
+
+	_, err := NewSnowflake(SnowflakeOpt{WorkerID: SnowflakeMaxWorkerID + 1})
+	assert.IsNotNil(err)
+
+	s, err := NewSnowflake(SnowflakeOpt{WorkerID: 42})
+	assert.IsNil(err)
+
+	var mu sync.Mutex
+	seen := map[int64]bool{}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 3000; j++ {
+				id, err := s.Next()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				mu.Lock()
+				seen[id] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(12000, len(seen))
+
+	id, err := s.Next()
+	assert.IsNil(err)
+	ts, worker, _ := s.Decompose(id)
+	assert.Equal(int64(42), worker)
+	assert.Greater(2*time.Second, time.Since(ts).Abs())
+}
+
+func TestSnowflakeClockBackwards(t *testing.T) {
+	assert := internal.NewAssert(t, "TestSnowflakeClockBackwards")
+
+	epoch := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := epoch.Add(time.Hour)
+	s, err := NewSnowflake(SnowflakeOpt{Epoch: epoch, WorkerID: 1, MaxClockBackward: 5 * time.Millisecond})
+	assert.IsNil(err)
+	s.now = func() time.Time {
+		now = now.Add(time.Millisecond)
+		return now
+	}
+
+	first, err := s.Next()
+	assert.IsNil(err)
+
+	// 回拨 3ms 时等待时钟追上
+	now = now.Add(-3 * time.Millisecond)
+	second, err := s.Next()
+	assert.IsNil(err)
+	assert.Less(first, second)
+
+	now = now.Add(-time.Second)
+	_, err = s.Next()
+	assert.Equal(true, errors.Is(err, ErrClockBackwards))
+
+	ts, worker, seq := s.Decompose(first)
+	assert.Equal(epoch.Add(time.Hour+time.Millisecond).UnixMilli(), ts.UnixMilli())
+	assert.Equal(int64(1), worker)
+	assert.Equal(int64(0), seq)
+}
+
+func TestNanoID(t *testing.T) {
+	assert := internal.NewAssert(t, "TestNanoID")
+
+	id, err := NanoID(0)
+	assert.IsNil(err)
+	assert.Equal(21, len(id))
+	for _, c := range id {
+		assert.Equal(true, strings.ContainsRune(NanoIDAlphabet, c))
+	}
+
+	id, err = NanoIDWithAlphabet("螃蟹ab", 10)
+	assert.IsNil(err)
+	assert.Equal(10, len([]rune(id)))
+	assert.Equal("", strings.Trim(id, "螃蟹ab"))
+
+	_, err = NanoIDWithAlphabet("a", 10)
+	assert.IsNotNil(err)
+	_, err = NanoIDWithAlphabet("abca", 10)
+	assert.IsNotNil(err)
+}
@@ -0,0 +1,102 @@
+package crab
+
+import (
+	"crypto/hkdf"
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+)
+
+// 与 AES 和 ChaCha20 函数配套的密钥长度
+const (
+	KeySizeAES128   = 16
+	KeySizeAES192   = 24
+	KeySizeAES256   = 32
+	KeySizeChaCha20 = chacha20poly1305.KeySize
+)
+
+// ScryptOpt scrypt 参数
+type ScryptOpt struct {
+	// N CPU/内存开销, 必须是大于 1 的 2 的幂, 默认 32768
+	N int
+	// R 块大小, 默认 8
+	R int
+	// P 并行度, 默认 1
+	P int
+}
+
+// defaultScryptOpt 2017 年 scrypt 文档推荐的交互式登录参数, 约占用 32 MiB 内存
+var defaultScryptOpt = ScryptOpt{N: 1 << 15, R: 8, P: 1}
+
+// HKDFSHA256 用 HKDF-SHA256 (RFC 5869) 从 secret 派生 length 字节的子密钥
+// secret 必须是随机的高熵密钥, 密码请使用 PBKDF2 或 Scrypt; info 区分不同用途的子密钥
+func HKDFSHA256(secret, salt []byte, info string, length int) ([]byte, error) {
+	return hkdfKey(sha256.New, secret, salt, info, length)
+}
+
+// HKDFSHA512 用 HKDF-SHA512 从 secret 派生 length 字节的子密钥
+func HKDFSHA512(secret, salt []byte, info string, length int) ([]byte, error) {
+	return hkdfKey(sha512.New, secret, salt, info, length)
+}
+
+// DeriveKey 用 HKDF-SHA256 从主密钥派生一个用途为 purpose 的子密钥, 如 "encryption"、"mac" 或租户 ID
+// size 通常为 KeySizeAES256 或 KeySizeChaCha20, 相同的主密钥和 purpose 总是得到相同的子密钥
+func DeriveKey(master []byte, purpose string, size int) ([]byte, error) {
+	return HKDFSHA256(master, nil, purpose, size)
+}
+
+// PBKDF2 用 PBKDF2 (RFC 8018) 从密码派生 keyLen 字节的密钥, alg 为 HMAC 使用的摘要算法
+// alg 只能是 SHA-1、SHA-2 或 SHA-3, 其他算法返回 ErrUnsupportedAlgorithm
+func PBKDF2(password string, salt []byte, iter, keyLen int, alg HashAlgorithm) ([]byte, error) {
+	switch alg {
+	case HashSHA1, HashSHA256, HashSHA512, HashSHA3256:
+	default:
+		return nil, fmt.Errorf("%w: pbkdf2 with %s", ErrUnsupportedAlgorithm, alg)
+	}
+	if iter < 1 {
+		return nil, fmt.Errorf("invalid pbkdf2 iterations %d", iter)
+	}
+	if keyLen < 1 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidKeySize, keyLen)
+	}
+	return pbkdf2.Key(func() hash.Hash {
+		h, _ := NewHash(alg)
+		return h
+	}, password, salt, iter, keyLen)
+}
+
+// Scrypt 用 scrypt (RFC 7914) 从密码派生 keyLen 字节的密钥, opt 的零值字段使用默认参数
+func Scrypt(password string, salt []byte, keyLen int, opt ScryptOpt) ([]byte, error) {
+	if opt.N == 0 {
+		opt.N = defaultScryptOpt.N
+	}
+	if opt.R == 0 {
+		opt.R = defaultScryptOpt.R
+	}
+	if opt.P == 0 {
+		opt.P = defaultScryptOpt.P
+	}
+	if keyLen < 1 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidKeySize, keyLen)
+	}
+	return scrypt.Key([]byte(password), salt, opt.N, opt.R, opt.P, keyLen)
+}
+
+func hkdfKey[H hash.Hash](h func() H, secret, salt []byte, info string, length int) ([]byte, error) {
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("%w: hkdf secret", ErrEmptyInput)
+	}
+	if length < 1 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidKeySize, length)
+	}
+	key, err := hkdf.Key(h, secret, salt, info, length)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKeySize, err)
+	}
+	return key, nil
+}
@@ -0,0 +1,121 @@
+package crab
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/serialt/crab/internal"
+)
+
+func TestHKDF(t *testing.T) {
+	assert := internal.NewAssert(t, "TestHKDF")
+
+	// RFC 5869 附录 A.1 和 A.3
+	ikm := bytes.Repeat([]byte{0x0b}, 22)
+	salt, _ := hex.DecodeString("000102030405060708090a0b0c")
+	info, _ := hex.DecodeString("f0f1f2f3f4f5f6f7f8f9")
+	key, err := HKDFSHA256(ikm, salt, string(info), 42)
+	assert.IsNil(err)
+	assert.Equal("3cb25f25faacd57a90434f64d0362f2a2d2d0a90cf1a5a4c5db02d56ecc4c5bf34007208d5b887185865", hex.EncodeToString(key))
+
+	key, err = HKDFSHA256(ikm, nil, "", 42)
+	assert.IsNil(err)
+	assert.Equal("8da4e775a563c18f715f802a063c5a31b8a11f5c5ee1879ec3454e5f3c738d2d9d201395faa4b61a96c8", hex.EncodeToString(key))
+
+	// RFC 5869 没有 SHA-512 的向量, 使用 A.1 和 A.3 的输入, 结果与 OpenSSL 一致
+	key, err = HKDFSHA512(ikm, salt, string(info), 42)
+	assert.IsNil(err)
+	assert.Equal("832390086cda71fb47625bb5ceb168e4c8e26a1a16ed34d9fc7fe92c1481579338da362cb8d9f925d7cb", hex.EncodeToString(key))
+
+	key, err = HKDFSHA512(ikm, nil, "", 42)
+	assert.IsNil(err)
+	assert.Equal("f5fa02b18298a72a8c23898a8703472c6eb179dc204c03425c970e3b164bf90fff22d04836d0e2343bac", hex.EncodeToString(key))
+
+	_, err = HKDFSHA256(nil, salt, "", 32)
+	assert.Equal(true, errors.Is(err, ErrEmptyInput))
+	_, err = HKDFSHA256(ikm, salt, "", 255*32+1)
+	assert.Equal(true, errors.Is(err, ErrInvalidKeySize))
+}
+
+func TestDeriveKey(t *testing.T) {
+	assert := internal.NewAssert(t, "TestDeriveKey")
+
+	master := GenerateKey(32)
+	encKey, err := DeriveKey(master, "encryption", KeySizeAES256)
+	assert.IsNil(err)
+	macKey, err := DeriveKey(master, "mac", KeySizeAES256)
+	assert.IsNil(err)
+	assert.NotEqual(encKey, macKey)
+	again, _ := DeriveKey(master, "encryption", KeySizeAES256)
+	assert.Equal(encKey, again)
+
+	// 派生的密钥可以直接用于 AES 和 ChaCha20
+	ciphertext, err := AESEncryptGCM([]byte("crab"), encKey)
+	assert.IsNil(err)
+	plaintext, err := AESDecryptGCM(ciphertext, encKey)
+	assert.IsNil(err)
+	assert.Equal("crab", string(plaintext))
+
+	chachaKey, _ := DeriveKey(master, "tenant-42", KeySizeChaCha20)
+	ciphertext, err = Chacha20AEADEncrypt([]byte("crab"), chachaKey)
+	assert.IsNil(err)
+	plaintext, err = Chacha20AEADDecrypt(ciphertext, chachaKey)
+	assert.IsNil(err)
+	assert.Equal("crab", string(plaintext))
+}
+
+func TestPBKDF2(t *testing.T) {
+	assert := internal.NewAssert(t, "TestPBKDF2")
+
+	// RFC 6070
+	cases := []struct {
+		iter     int
+		expected string
+	}{
+		{1, "0c60c80f961f0e71f3a9b524af6012062fe037a6"},
+		{2, "ea6c014dc72d6f8ccd1ed92ace1d41f0d8de8957"},
+		{4096, "4b007901b765489abead49d926f721d065a429c1"},
+	}
+	for _, c := range cases {
+		key, err := PBKDF2("password", []byte("salt"), c.iter, 20, HashSHA1)
+		assert.IsNil(err)
+		assert.Equal(c.expected, hex.EncodeToString(key))
+	}
+
+	// RFC 7914 第 11 节
+	key, err := PBKDF2("passwd", []byte("salt"), 1, 64, HashSHA256)
+	assert.IsNil(err)
+	assert.Equal("55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783", hex.EncodeToString(key))
+
+	// 非密码学摘要和已被攻破的摘要不能作为 PRF
+	for _, alg := range []HashAlgorithm{"md4", HashMD5, HashCRC32, HashBLAKE2b} {
+		_, err = PBKDF2("password", []byte("salt"), 1, 32, alg)
+		assert.Equal(true, errors.Is(err, ErrUnsupportedAlgorithm))
+	}
+	_, err = PBKDF2("password", []byte("salt"), 1, 32, HashSHA3256)
+	assert.IsNil(err)
+	_, err = PBKDF2("password", []byte("salt"), 0, 32, HashSHA256)
+	assert.IsNotNil(err)
+}
+
+func TestScrypt(t *testing.T) {
+	assert := internal.NewAssert(t, "TestScrypt")
+
+	// RFC 7914 第 12 节
+	key, err := Scrypt("", nil, 64, ScryptOpt{N: 16, R: 1, P: 1})
+	assert.IsNil(err)
+	assert.Equal("77d6576238657b203b19ca42c18a0497f16b4844e3074ae8dfdffa3fede21442fcd0069ded0948f8326a753a0fc81f17e8d3e0fb2e0d3628cf35e20c38d18906", hex.EncodeToString(key))
+
+	key, err = Scrypt("password", []byte("NaCl"), 64, ScryptOpt{N: 1024, R: 8, P: 16})
+	assert.IsNil(err)
+	assert.Equal("fdbabe1c9d3472007856e7190d01e9fe7c6ad7cbc8237830e77376634b3731622eaf30d92e22a3886ff109279d9830dac727afb94a83ee6d8360cbdfa2cc0640", hex.EncodeToString(key))
+
+	key, err = Scrypt("password", []byte("salt"), KeySizeAES256, ScryptOpt{})
+	assert.IsNil(err)
+	assert.Equal(32, len(key))
+
+	_, err = Scrypt("password", []byte("salt"), 32, ScryptOpt{N: 3})
+	assert.IsNotNil(err)
+}
@@ -0,0 +1,250 @@
+package crab
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// 叶子和内部节点使用不同的前缀, 防止把内部节点伪造成叶子 (RFC 6962)
+const (
+	merkleLeafPrefix = 0x00
+	merkleNodePrefix = 0x01
+	merkleRootPrefix = 0x02
+)
+
+// MerkleTree sha256 Merkle 树
+// 叶子节点为 sha256(0x00 | data), 内部节点为 sha256(0x01 | left | right),
+// 某一层节点数为奇数时最后一个节点直接提升到上一层,
+// 根为 sha256(0x02 | 叶子数 uint64 大端 | 顶层节点), 叶子数由根认证
+type MerkleTree struct {
+	// Names 目录树中每个叶子对应的文件路径, 相对目录并使用 / 分隔; 分块树为空
+	Names []string
+	// levels[0] 为叶子, 最后一层为根
+	levels [][][]byte
+}
+
+// MerkleProof 叶子到根的包含证明
+type MerkleProof struct {
+	// Index 叶子序号, 从 0 开始
+	Index int
+	// Total 叶子总数
+	Total int
+	// Siblings 从叶子到根每一层的兄弟节点, hex 编码
+	Siblings []string
+}
+
+// NewMerkleTree 用 leaves 中每一块数据作为叶子构建 Merkle 树
+func NewMerkleTree(leaves [][]byte) (*MerkleTree, error) {
+	hashes := make([][]byte, len(leaves))
+	for i, leaf := range leaves {
+		hashes[i] = merkleLeafHash(leaf)
+	}
+	return newMerkleTree(hashes)
+}
+
+// MerkleTreeFile 把文件按 chunkSize 分块, 每块作为一个叶子构建 Merkle 树
+// 文件流式读取, 只保存每块的摘要; 空文件按一个空块处理
+func MerkleTreeFile(path string, chunkSize int) (*MerkleTree, error) {
+	if chunkSize <= 0 {
+		return nil, fmt.Errorf("invalid chunk size %d", chunkSize)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var hashes [][]byte
+	buf := make([]byte, chunkSize)
+	for {
+		n, err := io.ReadFull(f, buf)
+		if n > 0 || len(hashes) == 0 && err == io.EOF {
+			hashes = append(hashes, merkleLeafHash(buf[:n]))
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return newMerkleTree(hashes)
+}
+
+// MerkleTreeDir 用目录下的所有文件构建 Merkle 树
+// 叶子按相对路径排序, 每个叶子的数据为 "相对路径\x00文件sha256", 文件改名或移动也会改变根
+func MerkleTreeDir(dir string) (*MerkleTree, error) {
+	paths, err := GetFilepaths(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	sums := map[string]string{}
+	names := make([]string, 0, len(paths))
+	for _, path := range paths {
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return nil, err
+		}
+		name := filepath.ToSlash(rel)
+		if sums[name], err = Sha256File(path); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	hashes := make([][]byte, len(names))
+	for i, name := range names {
+		hashes[i] = merkleLeafHash(merkleDirLeaf(name, sums[name]))
+	}
+	tree, err := newMerkleTree(hashes)
+	if err != nil {
+		return nil, err
+	}
+	tree.Names = names
+	return tree, nil
+}
+
+// Root 返回 hex 编码的根摘要
+func (t *MerkleTree) Root() string {
+	return hex.EncodeToString(merkleRootHash(t.Len(), t.levels[len(t.levels)-1][0]))
+}
+
+// Len 返回叶子数量
+func (t *MerkleTree) Len() int {
+	return len(t.levels[0])
+}
+
+// Proof 返回第 index 个叶子的包含证明
+func (t *MerkleTree) Proof(index int) (*MerkleProof, error) {
+	if index < 0 || index >= t.Len() {
+		return nil, fmt.Errorf("%w: leaf %d out of range", ErrInvalidProof, index)
+	}
+	proof := &MerkleProof{Index: index, Total: t.Len()}
+	for _, level := range t.levels[:len(t.levels)-1] {
+		if sibling := index ^ 1; sibling < len(level) {
+			proof.Siblings = append(proof.Siblings, hex.EncodeToString(level[sibling]))
+		}
+		index /= 2
+	}
+	return proof, nil
+}
+
+// ProofFor 返回目录树中文件 name 的包含证明, name 为相对路径
+func (t *MerkleTree) ProofFor(name string) (*MerkleProof, error) {
+	i := sort.SearchStrings(t.Names, name)
+	if i == len(t.Names) || t.Names[i] != name {
+		return nil, fmt.Errorf("%w: %s not in tree", ErrInvalidProof, name)
+	}
+	return t.Proof(i)
+}
+
+// VerifyMerkleProof 验证 data 是 root 对应的树中第 proof.Index 个叶子
+// 兄弟节点的左右位置由 Index 和 Total 推算, Total 包含在根中,
+// 所以证明无法挪用到其他位置或叶子数不同的树
+func VerifyMerkleProof(root string, data []byte, proof *MerkleProof) error {
+	return verifyMerkleProof(root, merkleLeafHash(data), proof)
+}
+
+// VerifyMerkleFile 验证 path 的内容是 root 对应目录树中的文件 name
+func VerifyMerkleFile(root, name, path string, proof *MerkleProof) error {
+	sum, err := Sha256File(path)
+	if err != nil {
+		return err
+	}
+	return VerifyMerkleProof(root, merkleDirLeaf(name, sum), proof)
+}
+
+func verifyMerkleProof(root string, leaf []byte, proof *MerkleProof) error {
+	if proof == nil || proof.Total <= 0 || proof.Index < 0 || proof.Index >= proof.Total {
+		return fmt.Errorf("%w: malformed proof", ErrInvalidProof)
+	}
+	want, err := hex.DecodeString(root)
+	if err != nil {
+		return fmt.Errorf("%w: malformed root", ErrInvalidProof)
+	}
+
+	hash, index, n, used := leaf, proof.Index, proof.Total, 0
+	for n > 1 {
+		if sibling := index ^ 1; sibling < n {
+			if used == len(proof.Siblings) {
+				return fmt.Errorf("%w: too few siblings", ErrInvalidProof)
+			}
+			node, err := hex.DecodeString(proof.Siblings[used])
+			if err != nil || len(node) != sha256.Size {
+				return fmt.Errorf("%w: malformed sibling %d", ErrInvalidProof, used)
+			}
+			used++
+			if index%2 == 0 {
+				hash = merkleNodeHash(hash, node)
+			} else {
+				hash = merkleNodeHash(node, hash)
+			}
+		}
+		index /= 2
+		n = (n + 1) / 2
+	}
+	if used != len(proof.Siblings) {
+		return fmt.Errorf("%w: too many siblings", ErrInvalidProof)
+	}
+	if !bytes.Equal(merkleRootHash(proof.Total, hash), want) {
+		return fmt.Errorf("%w: root mismatch", ErrInvalidProof)
+	}
+	return nil
+}
+
+func newMerkleTree(leaves [][]byte) (*MerkleTree, error) {
+	if len(leaves) == 0 {
+		return nil, fmt.Errorf("%w: no leaves", ErrEmptyInput)
+	}
+	levels := [][][]byte{leaves}
+	for level := leaves; len(level) > 1; {
+		next := make([][]byte, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+			} else {
+				next = append(next, merkleNodeHash(level[i], level[i+1]))
+			}
+		}
+		levels = append(levels, next)
+		level = next
+	}
+	return &MerkleTree{levels: levels}, nil
+}
+
+func merkleLeafHash(data []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{merkleLeafPrefix})
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+func merkleNodeHash(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{merkleNodePrefix})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// merkleRootHash 把叶子数加入根, 否则证明中的 Total 无法认证
+func merkleRootHash(total int, top []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{merkleRootPrefix})
+	h.Write(binary.BigEndian.AppendUint64(nil, uint64(total)))
+	h.Write(top)
+	return h.Sum(nil)
+}
+
+func merkleDirLeaf(name, sum string) []byte {
+	return []byte(name + "\x00" + sum)
+}
@@ -0,0 +1,139 @@
+package crab
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/serialt/crab/internal"
+)
+
+func TestMerkleTree(t *testing.T) {
+	assert := internal.NewAssert(t, "TestMerkleTree")
+
+	var leaves [][]byte
+	for i := 0; i < 7; i++ {
+		leaves = append(leaves, bytes.Repeat([]byte{byte(i)}, i+1))
+	}
+	tree, err := NewMerkleTree(leaves)
+	assert.IsNil(err)
+	assert.Equal(7, tree.Len())
+
+	for i, leaf := range leaves {
+		proof, err := tree.Proof(i)
+		assert.IsNil(err)
+		assert.IsNil(VerifyMerkleProof(tree.Root(), leaf, proof))
+	}
+
+	proof, _ := tree.Proof(2)
+	err = VerifyMerkleProof(tree.Root(), leaves[3], proof)
+	assert.Equal(true, errors.Is(err, ErrInvalidProof))
+
+	proof.Index = 3
+	err = VerifyMerkleProof(tree.Root(), leaves[2], proof)
+	assert.Equal(true, errors.Is(err, ErrInvalidProof))
+
+	proof, _ = tree.Proof(6)
+	proof.Siblings = append(proof.Siblings, proof.Siblings[0])
+	err = VerifyMerkleProof(tree.Root(), leaves[6], proof)
+	assert.Equal(true, errors.Is(err, ErrInvalidProof))
+
+	_, err = tree.Proof(7)
+	assert.Equal(true, errors.Is(err, ErrInvalidProof))
+
+	_, err = NewMerkleTree(nil)
+	assert.Equal(true, errors.Is(err, ErrEmptyInput))
+
+	single, err := NewMerkleTree(leaves[:1])
+	assert.IsNil(err)
+	proof, _ = single.Proof(0)
+	assert.Equal(0, len(proof.Siblings))
+	assert.IsNil(VerifyMerkleProof(single.Root(), leaves[0], proof))
+}
+
+func TestMerkleProofReplay(t *testing.T) {
+	assert := internal.NewAssert(t, "TestMerkleProofReplay")
+
+	leaves := [][]byte{[]byte("a"), []byte("b"), []byte("c")}
+	tree, err := NewMerkleTree(leaves)
+	assert.IsNil(err)
+
+	// c 是第 3 个叶子, 它的证明在 Total=2 时兄弟节点刚好是 hash(a, b), 不能当作第 2 个叶子通过
+	proof, err := tree.Proof(2)
+	assert.IsNil(err)
+	assert.IsNil(VerifyMerkleProof(tree.Root(), leaves[2], proof))
+	proof.Index, proof.Total = 1, 2
+	err = VerifyMerkleProof(tree.Root(), leaves[2], proof)
+	assert.Equal(true, errors.Is(err, ErrInvalidProof))
+
+	for _, moved := range []MerkleProof{{Index: 0, Total: 3}, {Index: 1, Total: 3}, {Index: 0, Total: 1}} {
+		p, _ := tree.Proof(2)
+		p.Index, p.Total = moved.Index, moved.Total
+		err = VerifyMerkleProof(tree.Root(), leaves[2], p)
+		assert.Equal(true, errors.Is(err, ErrInvalidProof))
+	}
+}
+
+func TestMerkleTreeFile(t *testing.T) {
+	assert := internal.NewAssert(t, "TestMerkleTreeFile")
+
+	data := bytes.Repeat([]byte("crab merkle "), 100)
+	path := filepath.Join(t.TempDir(), "large.bin")
+	assert.IsNil(os.WriteFile(path, data, 0644))
+
+	tree, err := MerkleTreeFile(path, 256)
+	assert.IsNil(err)
+	assert.Equal(5, tree.Len())
+
+	var chunks [][]byte
+	for off := 0; off < len(data); off += 256 {
+		chunks = append(chunks, data[off:min(off+256, len(data))])
+	}
+	expected, _ := NewMerkleTree(chunks)
+	assert.Equal(expected.Root(), tree.Root())
+
+	proof, err := tree.Proof(4)
+	assert.IsNil(err)
+	assert.IsNil(VerifyMerkleProof(tree.Root(), chunks[4], proof))
+	assert.IsNotNil(VerifyMerkleProof(tree.Root(), chunks[3], proof))
+
+	empty := filepath.Join(t.TempDir(), "empty")
+	assert.IsNil(os.WriteFile(empty, nil, 0644))
+	tree, err = MerkleTreeFile(empty, 256)
+	assert.IsNil(err)
+	assert.Equal(1, tree.Len())
+
+	_, err = MerkleTreeFile(path, 0)
+	assert.IsNotNil(err)
+}
+
+func TestMerkleTreeDir(t *testing.T) {
+	assert := internal.NewAssert(t, "TestMerkleTreeDir")
+
+	dir := t.TempDir()
+	assert.IsNil(os.MkdirAll(filepath.Join(dir, "sub"), 0755))
+	assert.IsNil(os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0644))
+	assert.IsNil(os.WriteFile(filepath.Join(dir, "sub", "b.txt"), []byte("b"), 0644))
+	assert.IsNil(os.WriteFile(filepath.Join(dir, "c.txt"), []byte("c"), 0644))
+
+	tree, err := MerkleTreeDir(dir)
+	assert.IsNil(err)
+	assert.Equal([]string{"a.txt", "c.txt", "sub/b.txt"}, tree.Names)
+	root := tree.Root()
+
+	proof, err := tree.ProofFor("sub/b.txt")
+	assert.IsNil(err)
+	assert.IsNil(VerifyMerkleFile(root, "sub/b.txt", filepath.Join(dir, "sub", "b.txt"), proof))
+	err = VerifyMerkleFile(root, "sub/b.txt", filepath.Join(dir, "a.txt"), proof)
+	assert.Equal(true, errors.Is(err, ErrInvalidProof))
+
+	_, err = tree.ProofFor("missing.txt")
+	assert.Equal(true, errors.Is(err, ErrInvalidProof))
+
+	assert.IsNil(os.Rename(filepath.Join(dir, "c.txt"), filepath.Join(dir, "d.txt")))
+	tree, err = MerkleTreeDir(dir)
+	assert.IsNil(err)
+	assert.NotEqual(root, tree.Root())
+}
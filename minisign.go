@@ -0,0 +1,381 @@
+package crab
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/scrypt"
+)
+
+// minisign 格式中的算法标识
+var (
+	// minisignAlgLegacy 直接对文件内容签名
+	minisignAlgLegacy = [2]byte{'E', 'd'}
+	// minisignAlgHashed 对文件的 BLAKE2b-512 签名, minisign 0.8 起的默认方式, 支持任意大小的文件
+	minisignAlgHashed = [2]byte{'E', 'D'}
+	minisignKDFScrypt = [2]byte{'S', 'c'}
+	minisignChecksum  = [2]byte{'B', '2'}
+)
+
+const (
+	// MinisignSigExt minisign 签名文件的后缀
+	MinisignSigExt = ".minisig"
+
+	minisignKeyIDSize  = 8
+	minisignSaltSize   = 32
+	minisignKeynumSize = minisignKeyIDSize + ed25519.PrivateKeySize + blake2b.Size256
+	minisignCommentPfx = "untrusted comment: "
+	minisignTrustedPfx = "trusted comment: "
+)
+
+// minisign 默认的 scrypt 参数, 与 libsodium 的 OPSLIMIT_SENSITIVE 和 MEMLIMIT_SENSITIVE 一致, 约占用 1 GiB 内存
+var (
+	minisignOpsLimit uint64 = 33554432
+	minisignMemLimit uint64 = 1073741824
+)
+
+// 解析密钥文件时允许的最大 scrypt 参数, 防止恶意的密钥文件耗尽内存
+// opslimit 的上限使 memlimit 取最大值时 p 仍为 1
+const (
+	minisignMaxMemLimit uint64 = 4 << 30
+	minisignMaxOpsLimit        = minisignMaxMemLimit / 32
+)
+
+// MinisignPublicKey minisign 公钥
+type MinisignPublicKey struct {
+	KeyID [minisignKeyIDSize]byte
+	Key   ed25519.PublicKey
+}
+
+// MinisignPrivateKey minisign 私钥
+type MinisignPrivateKey struct {
+	KeyID [minisignKeyIDSize]byte
+	Key   ed25519.PrivateKey
+}
+
+// MinisignSignature minisign 签名, 对应 .minisig 文件
+type MinisignSignature struct {
+	Algorithm [2]byte
+	KeyID     [minisignKeyIDSize]byte
+	Signature [ed25519.SignatureSize]byte
+	// UntrustedComment 不受签名保护的注释
+	UntrustedComment string
+	// TrustedComment 受签名保护的注释, 验证通过后才可信
+	TrustedComment  string
+	GlobalSignature [ed25519.SignatureSize]byte
+}
+
+// GenerateMinisignKey 生成 minisign 密钥对
+func GenerateMinisignKey() (*MinisignPublicKey, *MinisignPrivateKey, error) {
+	pub, priv, err := ed25519.GenerateKey(RandReader())
+	if err != nil {
+		return nil, nil, err
+	}
+	var keyID [minisignKeyIDSize]byte
+	if err := randRead(keyID[:]); err != nil {
+		return nil, nil, err
+	}
+	return &MinisignPublicKey{KeyID: keyID, Key: pub}, &MinisignPrivateKey{KeyID: keyID, Key: priv}, nil
+}
+
+// KeyIDHex 与 minisign 显示的 key id 一致, 按小端序输出的大写 hex
+func (pk *MinisignPublicKey) KeyIDHex() string {
+	return minisignKeyIDHex(pk.KeyID)
+}
+
+// Base64 返回公钥的 base64 编码, 可以用于 minisign -P
+func (pk *MinisignPublicKey) Base64() string {
+	raw := append(append(minisignAlgLegacy[:], pk.KeyID[:]...), pk.Key...)
+	return base64.StdEncoding.EncodeToString(raw)
+}
+
+// String 返回 minisign.pub 文件的内容
+func (pk *MinisignPublicKey) String() string {
+	return minisignCommentPfx + "minisign public key " + pk.KeyIDHex() + "\n" + pk.Base64() + "\n"
+}
+
+// ParseMinisignPublicKey 解析 minisign.pub 文件的内容或单行的 base64 公钥
+func ParseMinisignPublicKey(s string) (*MinisignPublicKey, error) {
+	line := strings.TrimSpace(s)
+	if lines := minisignLines(s); len(lines) >= 2 && strings.HasPrefix(lines[0], minisignCommentPfx) {
+		line = lines[1]
+	}
+	raw, err := base64.StdEncoding.DecodeString(line)
+	if err != nil || len(raw) != 2+minisignKeyIDSize+ed25519.PublicKeySize || !bytes.Equal(raw[:2], minisignAlgLegacy[:]) {
+		return nil, fmt.Errorf("%w: minisign public key", ErrInvalidKey)
+	}
+	pk := &MinisignPublicKey{Key: ed25519.PublicKey(raw[2+minisignKeyIDSize:])}
+	copy(pk.KeyID[:], raw[2:])
+	return pk, nil
+}
+
+// Public 返回对应的公钥
+func (sk *MinisignPrivateKey) Public() *MinisignPublicKey {
+	return &MinisignPublicKey{KeyID: sk.KeyID, Key: sk.Key.Public().(ed25519.PublicKey)}
+}
+
+// Marshal 返回 minisign.key 文件的内容, 私钥用 password 经 scrypt 派生的密钥加密
+// password 为空时不加密, 与 minisign -W 生成的文件相同
+func (sk *MinisignPrivateKey) Marshal(password string) ([]byte, error) {
+	keynum := make([]byte, 0, minisignKeynumSize)
+	keynum = append(keynum, sk.KeyID[:]...)
+	keynum = append(keynum, sk.Key...)
+	keynum = append(keynum, minisignKeyChecksum(sk.KeyID, sk.Key)...)
+
+	var kdf [2]byte
+	salt := make([]byte, minisignSaltSize)
+	ops, mem := uint64(0), uint64(0)
+	if password != "" {
+		kdf, ops, mem = minisignKDFScrypt, minisignOpsLimit, minisignMemLimit
+		if err := randRead(salt); err != nil {
+			return nil, err
+		}
+		stream, err := minisignKDF(password, salt, ops, mem)
+		if err != nil {
+			return nil, err
+		}
+		subtle.XORBytes(keynum, keynum, stream)
+	}
+
+	var buf bytes.Buffer
+	buf.Write(minisignAlgLegacy[:])
+	buf.Write(kdf[:])
+	buf.Write(minisignChecksum[:])
+	buf.Write(salt)
+	binary.Write(&buf, binary.LittleEndian, ops)
+	binary.Write(&buf, binary.LittleEndian, mem)
+	buf.Write(keynum)
+
+	comment := "minisign encrypted secret key"
+	if password == "" {
+		comment = "minisign secret key"
+	}
+	return []byte(minisignCommentPfx + comment + "\n" + base64.StdEncoding.EncodeToString(buf.Bytes()) + "\n"), nil
+}
+
+// ParseMinisignPrivateKey 解析 minisign.key 文件, 密码错误时返回 ErrPasswordMismatch
+func ParseMinisignPrivateKey(data []byte, password string) (*MinisignPrivateKey, error) {
+	lines := minisignLines(string(data))
+	if len(lines) < 2 {
+		return nil, fmt.Errorf("%w: minisign secret key", ErrInvalidKey)
+	}
+	raw, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil || len(raw) != 6+minisignSaltSize+16+minisignKeynumSize ||
+		!bytes.Equal(raw[:2], minisignAlgLegacy[:]) || !bytes.Equal(raw[4:6], minisignChecksum[:]) {
+		return nil, fmt.Errorf("%w: minisign secret key", ErrInvalidKey)
+	}
+	kdf, salt := raw[2:4], raw[6:6+minisignSaltSize]
+	ops := binary.LittleEndian.Uint64(raw[6+minisignSaltSize:])
+	mem := binary.LittleEndian.Uint64(raw[14+minisignSaltSize:])
+	keynum := bytes.Clone(raw[22+minisignSaltSize:])
+
+	switch {
+	case bytes.Equal(kdf, minisignKDFScrypt[:]):
+		stream, err := minisignKDF(password, salt, ops, mem)
+		if err != nil {
+			return nil, err
+		}
+		subtle.XORBytes(keynum, keynum, stream)
+	case kdf[0] != 0 || kdf[1] != 0:
+		return nil, fmt.Errorf("%w: minisign kdf %q", ErrUnsupportedAlgorithm, kdf)
+	}
+
+	sk := &MinisignPrivateKey{Key: ed25519.PrivateKey(keynum[minisignKeyIDSize : minisignKeyIDSize+ed25519.PrivateKeySize])}
+	copy(sk.KeyID[:], keynum)
+	if subtle.ConstantTimeCompare(minisignKeyChecksum(sk.KeyID, sk.Key), keynum[minisignKeyIDSize+ed25519.PrivateKeySize:]) != 1 {
+		return nil, ErrPasswordMismatch
+	}
+	return sk, nil
+}
+
+// Sign 对 r 的内容签名, 使用 minisign 默认的预哈希方式
+// 可信注释在 .minisig 中占一行, 不能包含换行
+func (sk *MinisignPrivateKey) Sign(r io.Reader, trustedComment string) (*MinisignSignature, error) {
+	if strings.ContainsAny(trustedComment, "\r\n") {
+		return nil, fmt.Errorf("%w: trusted comment must be a single line", ErrInvalidSignature)
+	}
+	if len(sk.Key) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("%w: minisign secret key", ErrInvalidKey)
+	}
+	h, _ := blake2b.New512(nil)
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, err
+	}
+	sig := &MinisignSignature{
+		Algorithm:        minisignAlgHashed,
+		KeyID:            sk.KeyID,
+		UntrustedComment: "signature from minisign secret key",
+		TrustedComment:   trustedComment,
+	}
+	copy(sig.Signature[:], ed25519.Sign(sk.Key, h.Sum(nil)))
+	copy(sig.GlobalSignature[:], ed25519.Sign(sk.Key, append(sig.Signature[:], trustedComment...)))
+	return sig, nil
+}
+
+// SignFile 对文件签名并写入 path.minisig, trustedComment 为空时与 minisign 一样使用时间戳和文件名
+func (sk *MinisignPrivateKey) SignFile(path, trustedComment string) (*MinisignSignature, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if trustedComment == "" {
+		trustedComment = fmt.Sprintf("timestamp:%d\tfile:%s\thashed", time.Now().Unix(), filepath.Base(path))
+	}
+	sig, err := sk.Sign(f, trustedComment)
+	if err != nil {
+		return nil, err
+	}
+	return sig, os.WriteFile(path+MinisignSigExt, []byte(sig.String()), 0644)
+}
+
+// Verify 验证 r 的内容和签名中的可信注释, 失败时返回 ErrInvalidSignature
+// 同时支持预哈希签名和旧版直接签名
+func (pk *MinisignPublicKey) Verify(r io.Reader, sig *MinisignSignature) error {
+	if len(pk.Key) != ed25519.PublicKeySize {
+		return fmt.Errorf("%w: minisign public key", ErrInvalidKey)
+	}
+	if sig.KeyID != pk.KeyID {
+		return fmt.Errorf("%w: signed by key %s, not %s", ErrInvalidSignature, minisignKeyIDHex(sig.KeyID), pk.KeyIDHex())
+	}
+
+	var message []byte
+	switch sig.Algorithm {
+	case minisignAlgHashed:
+		h, _ := blake2b.New512(nil)
+		if _, err := io.Copy(h, r); err != nil {
+			return err
+		}
+		message = h.Sum(nil)
+	case minisignAlgLegacy:
+		var err error
+		if message, err = io.ReadAll(r); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("%w: minisign signature %q", ErrUnsupportedAlgorithm, sig.Algorithm[:])
+	}
+
+	if !ed25519.Verify(pk.Key, message, sig.Signature[:]) {
+		return ErrInvalidSignature
+	}
+	if !ed25519.Verify(pk.Key, append(bytes.Clone(sig.Signature[:]), sig.TrustedComment...), sig.GlobalSignature[:]) {
+		return fmt.Errorf("%w: trusted comment", ErrInvalidSignature)
+	}
+	return nil
+}
+
+// VerifyFile 用 path.minisig 验证文件, 返回验证通过的签名, 可以读取其中的可信注释
+func (pk *MinisignPublicKey) VerifyFile(path string) (*MinisignSignature, error) {
+	data, err := os.ReadFile(path + MinisignSigExt)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := ParseMinisignSignature(string(data))
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return sig, pk.Verify(f, sig)
+}
+
+// String 返回 .minisig 文件的内容
+func (sig *MinisignSignature) String() string {
+	raw := append(append(sig.Algorithm[:], sig.KeyID[:]...), sig.Signature[:]...)
+	return minisignCommentPfx + sig.UntrustedComment + "\n" +
+		base64.StdEncoding.EncodeToString(raw) + "\n" +
+		minisignTrustedPfx + sig.TrustedComment + "\n" +
+		base64.StdEncoding.EncodeToString(sig.GlobalSignature[:]) + "\n"
+}
+
+// ParseMinisignSignature 解析 .minisig 文件的内容
+func ParseMinisignSignature(s string) (*MinisignSignature, error) {
+	lines := minisignLines(s)
+	if len(lines) < 4 || !strings.HasPrefix(lines[0], minisignCommentPfx) || !strings.HasPrefix(lines[2], minisignTrustedPfx) {
+		return nil, fmt.Errorf("%w: malformed minisign signature", ErrInvalidSignature)
+	}
+	raw, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil || len(raw) != 2+minisignKeyIDSize+ed25519.SignatureSize {
+		return nil, fmt.Errorf("%w: malformed minisign signature", ErrInvalidSignature)
+	}
+	global, err := base64.StdEncoding.DecodeString(lines[3])
+	if err != nil || len(global) != ed25519.SignatureSize {
+		return nil, fmt.Errorf("%w: malformed minisign global signature", ErrInvalidSignature)
+	}
+
+	sig := &MinisignSignature{
+		UntrustedComment: strings.TrimPrefix(lines[0], minisignCommentPfx),
+		TrustedComment:   strings.TrimPrefix(lines[2], minisignTrustedPfx),
+	}
+	copy(sig.Algorithm[:], raw)
+	copy(sig.KeyID[:], raw[2:])
+	copy(sig.Signature[:], raw[2+minisignKeyIDSize:])
+	copy(sig.GlobalSignature[:], global)
+	return sig, nil
+}
+
+func minisignKeyIDHex(keyID [minisignKeyIDSize]byte) string {
+	return fmt.Sprintf("%016X", binary.LittleEndian.Uint64(keyID[:]))
+}
+
+// minisignKeyChecksum BLAKE2b-256(算法 | key id | 私钥)
+func minisignKeyChecksum(keyID [minisignKeyIDSize]byte, key ed25519.PrivateKey) []byte {
+	h, _ := blake2b.New256(nil)
+	h.Write(minisignAlgLegacy[:])
+	h.Write(keyID[:])
+	h.Write(key)
+	return h.Sum(nil)
+}
+
+// minisignKDF 与 libsodium crypto_pwhash_scryptsalsa208sha256 一样, 把 opslimit 和 memlimit 换算为 scrypt 参数
+func minisignKDF(password string, salt []byte, opsLimit, memLimit uint64) ([]byte, error) {
+	if opsLimit > minisignMaxOpsLimit || memLimit > minisignMaxMemLimit {
+		return nil, fmt.Errorf("%w: minisign scrypt limits too large", ErrInvalidKey)
+	}
+	if opsLimit < 32768 {
+		opsLimit = 32768
+	}
+	r, p, nLog2 := uint64(8), uint64(1), uint64(1)
+	maxN := opsLimit / (r * 4)
+	if opsLimit >= memLimit/32 {
+		maxN = memLimit / (r * 128)
+	}
+	for ; nLog2 < 63; nLog2++ {
+		if uint64(1)<<nLog2 > maxN/2 {
+			break
+		}
+	}
+	if opsLimit >= memLimit/32 {
+		maxRP := min((opsLimit/4)/(uint64(1)<<nLog2), 0x3fffffff)
+		p = maxRP / r
+	}
+	return scrypt.Key([]byte(password), salt, 1<<nLog2, int(r), int(p), minisignKeynumSize)
+}
+
+// minisignLines 返回去掉行尾 \r 的非空行
+func minisignLines(s string) []string {
+	var lines []string
+	scanner := bufio.NewScanner(strings.NewReader(s))
+	for scanner.Scan() {
+		if line := strings.TrimRight(scanner.Text(), "\r"); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,249 @@
+package crab
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/serialt/crab/internal"
+)
+
+func TestMinisign(t *testing.T) {
+	assert := internal.NewAssert(t, "TestMinisign")
+
+	pk, sk, err := GenerateMinisignKey()
+	assert.IsNil(err)
+	assert.Equal(pk, sk.Public())
+
+	// 公钥可以解析 minisign.pub 文件, 也可以解析单行 base64
+	parsed, err := ParseMinisignPublicKey(pk.String())
+	assert.IsNil(err)
+	assert.Equal(pk, parsed)
+	parsed, err = ParseMinisignPublicKey(pk.Base64())
+	assert.IsNil(err)
+	assert.Equal(pk, parsed)
+
+	data := []byte("release v1.2.3")
+	sig, err := sk.Sign(bytes.NewReader(data), "timestamp:1700000000\tfile:release.tgz\thashed")
+	assert.IsNil(err)
+	assert.Equal(minisignAlgHashed, sig.Algorithm)
+
+	sig, err = ParseMinisignSignature(sig.String())
+	assert.IsNil(err)
+	assert.IsNil(pk.Verify(bytes.NewReader(data), sig))
+	assert.Equal("timestamp:1700000000\tfile:release.tgz\thashed", sig.TrustedComment)
+
+	// 篡改内容
+	err = pk.Verify(strings.NewReader("release v1.2.4"), sig)
+	assert.Equal(true, errors.Is(err, ErrInvalidSignature))
+
+	// 篡改可信注释
+	forged := *sig
+	forged.TrustedComment = "timestamp:1700000000\tfile:other.tgz\thashed"
+	err = pk.Verify(bytes.NewReader(data), &forged)
+	assert.Equal(true, errors.Is(err, ErrInvalidSignature))
+
+	// 其他密钥
+	otherPK, _, err := GenerateMinisignKey()
+	assert.IsNil(err)
+	err = otherPK.Verify(bytes.NewReader(data), sig)
+	assert.Equal(true, errors.Is(err, ErrInvalidSignature))
+
+	// 零值密钥返回错误而不是 panic
+	err = (&MinisignPublicKey{}).Verify(bytes.NewReader(data), sig)
+	assert.Equal(true, errors.Is(err, ErrInvalidKey))
+	_, err = (&MinisignPrivateKey{}).Sign(bytes.NewReader(data), "")
+	assert.Equal(true, errors.Is(err, ErrInvalidKey))
+
+	// 可信注释包含换行时生成的 .minisig 无法解析
+	_, err = sk.Sign(bytes.NewReader(data), "timestamp:1700000000\ntrusted comment: forged")
+	assert.Equal(true, errors.Is(err, ErrInvalidSignature))
+	_, err = sk.Sign(bytes.NewReader(data), "a\rb")
+	assert.Equal(true, errors.Is(err, ErrInvalidSignature))
+
+	_, err = ParseMinisignSignature("untrusted comment: x\nnot base64\n")
+	assert.Equal(true, errors.Is(err, ErrInvalidSignature))
+}
+
+func TestMinisignLegacy(t *testing.T) {
+	assert := internal.NewAssert(t, "TestMinisignLegacy")
+
+	pk, sk, err := GenerateMinisignKey()
+	assert.IsNil(err)
+
+	// 旧版 minisign 直接对内容签名
+	data := []byte("legacy")
+	sig := &MinisignSignature{Algorithm: minisignAlgLegacy, KeyID: sk.KeyID, TrustedComment: "legacy"}
+	copy(sig.Signature[:], ed25519.Sign(sk.Key, data))
+	copy(sig.GlobalSignature[:], ed25519.Sign(sk.Key, append(sig.Signature[:], sig.TrustedComment...)))
+	assert.IsNil(pk.Verify(bytes.NewReader(data), sig))
+
+	sig.Algorithm = [2]byte{'X', 'x'}
+	err = pk.Verify(bytes.NewReader(data), sig)
+	assert.Equal(true, errors.Is(err, ErrUnsupportedAlgorithm))
+}
+
+func TestMinisignPrivateKey(t *testing.T) {
+	assert := internal.NewAssert(t, "TestMinisignPrivateKey")
+
+	// 测试中使用较小的 scrypt 参数
+	ops, mem := minisignOpsLimit, minisignMemLimit
+	minisignOpsLimit, minisignMemLimit = 32768, 16*1024*1024
+	defer func() { minisignOpsLimit, minisignMemLimit = ops, mem }()
+
+	_, sk, err := GenerateMinisignKey()
+	assert.IsNil(err)
+
+	data, err := sk.Marshal("correct horse")
+	assert.IsNil(err)
+	assert.Equal(true, strings.HasPrefix(string(data), "untrusted comment: minisign encrypted secret key\n"))
+
+	parsed, err := ParseMinisignPrivateKey(data, "correct horse")
+	assert.IsNil(err)
+	assert.Equal(sk, parsed)
+
+	_, err = ParseMinisignPrivateKey(data, "wrong")
+	assert.Equal(ErrPasswordMismatch, err)
+
+	// 不加密
+	data, err = sk.Marshal("")
+	assert.IsNil(err)
+	parsed, err = ParseMinisignPrivateKey(data, "")
+	assert.IsNil(err)
+	assert.Equal(sk, parsed)
+
+	_, err = ParseMinisignPrivateKey([]byte("untrusted comment: x\nRWQ=\n"), "")
+	assert.Equal(true, errors.Is(err, ErrInvalidKey))
+
+	// 恶意的 scrypt 参数和未知的校验和算法
+	raw, err := base64.StdEncoding.DecodeString(minisignLines(string(data))[1])
+	assert.IsNil(err)
+	huge := bytes.Clone(raw)
+	copy(huge[2:], minisignKDFScrypt[:])
+	binary.LittleEndian.PutUint64(huge[6+minisignSaltSize:], 1<<62)
+	binary.LittleEndian.PutUint64(huge[14+minisignSaltSize:], 1<<62)
+	_, err = ParseMinisignPrivateKey([]byte("untrusted comment: x\n"+base64.StdEncoding.EncodeToString(huge)+"\n"), "pw")
+	assert.Equal(true, errors.Is(err, ErrInvalidKey))
+	checksum := bytes.Clone(raw)
+	copy(checksum[4:], "XX")
+	_, err = ParseMinisignPrivateKey([]byte("untrusted comment: x\n"+base64.StdEncoding.EncodeToString(checksum)+"\n"), "")
+	assert.Equal(true, errors.Is(err, ErrInvalidKey))
+}
+
+func TestMinisignKDF(t *testing.T) {
+	assert := internal.NewAssert(t, "TestMinisignKDF")
+
+	// minisign 默认参数对应 N=2^20, r=8, p=1, 这里只比较换算结果, 用小参数计算
+	key, err := minisignKDF("pw", make([]byte, minisignSaltSize), 32768, 16*1024*1024)
+	assert.IsNil(err)
+	expected, err := Scrypt("pw", make([]byte, minisignSaltSize), minisignKeynumSize, ScryptOpt{N: 1024, R: 8, P: 1})
+	assert.IsNil(err)
+	assert.Equal(expected, key)
+
+	key, err = minisignKDF("pw", make([]byte, minisignSaltSize), 1<<20, 1<<20)
+	assert.IsNil(err)
+	expected, err = Scrypt("pw", make([]byte, minisignSaltSize), minisignKeynumSize, ScryptOpt{N: 1 << 10, R: 8, P: 32})
+	assert.IsNil(err)
+	assert.Equal(expected, key)
+}
+
+func TestMinisignPublicKeyCompat(t *testing.T) {
+	assert := internal.NewAssert(t, "TestMinisignPublicKeyCompat")
+
+	// minisign 项目发布时使用的公钥
+	pk, err := ParseMinisignPublicKey("RWQf6LRCGA9i53mlYecO4IzT51TGPpvWucNSCh1CBM0QTaLn73Y7GFO3")
+	assert.IsNil(err)
+	assert.Equal("E7620F1842B4E81F", pk.KeyIDHex())
+	assert.Equal("RWQf6LRCGA9i53mlYecO4IzT51TGPpvWucNSCh1CBM0QTaLn73Y7GFO3", pk.Base64())
+
+	_, err = ParseMinisignPublicKey("RWQf6LRCGA9i53ml")
+	assert.Equal(true, errors.Is(err, ErrInvalidKey))
+}
+
+func TestMinisignCLISignature(t *testing.T) {
+	assert := internal.NewAssert(t, "TestMinisignCLISignature")
+
+	// minisign 作者用项目发布的密钥对内容 "test" 签名, 取自 github.com/jedisct1/go-minisign 的测试
+	pk, err := ParseMinisignPublicKey("RWQf6LRCGA9i53mlYecO4IzT51TGPpvWucNSCh1CBM0QTaLn73Y7GFO3")
+	assert.IsNil(err)
+	signatures := map[string]string{
+		"timestamp:1635443258\tfile:test\thashed": "untrusted comment: signature from minisign secret key\n" +
+			"RUQf6LRCGA9i559r3g7V1qNyJDApGip8MfqcadIgT9CuhV3EMhHoN1mGTkUidF/z7SrlQgXdy8ofjb7bNJJylDOocrCo8KLzZwo=\n" +
+			"trusted comment: timestamp:1635443258\tfile:test\thashed\n" +
+			"/cj37GK60vryibFn+ftOgbCvW9NKhKYgjVpFFQUcWPAnjO23wrvVDTt7cloNC06maoBli9q6qwZDXXoaxweICQ==\n",
+		"timestamp:1635442742\tfile:test": "untrusted comment: signature from minisign secret key\n" +
+			"RWQf6LRCGA9i59SLOFxz6NxvASXDJeRtuZykwQepbDEGt87ig1BNpWaVWuNrm73YiIiJbq71Wi+dP9eKL8OC351vwIasSSbXxwA=\n" +
+			"trusted comment: timestamp:1635442742\tfile:test\n" +
+			"0YteLgV960ia80vnA/fHbvkyjl/IoP/HNOCaZfrF0CdhAlp7ok+Tpkya+VpWPX5C/Is3q8a/kEDSY7fBmmgJCg==\n",
+	}
+	for comment, content := range signatures {
+		sig, err := ParseMinisignSignature(content)
+		assert.IsNil(err)
+		assert.Equal(comment, sig.TrustedComment)
+		assert.IsNil(pk.Verify(strings.NewReader("test"), sig))
+		assert.Equal(content, sig.String())
+
+		err = pk.Verify(strings.NewReader("tesT"), sig)
+		assert.Equal(true, errors.Is(err, ErrInvalidSignature))
+
+		// 全局签名覆盖可信注释
+		sig.TrustedComment = strings.Replace(sig.TrustedComment, "file:test", "file:prod", 1)
+		err = pk.Verify(strings.NewReader("test"), sig)
+		assert.Equal(true, errors.Is(err, ErrInvalidSignature))
+	}
+}
+
+func TestMinisignThirdPartyKey(t *testing.T) {
+	if testing.Short() {
+		t.Skip("minisign default scrypt parameters use 1 GiB of memory")
+	}
+	assert := internal.NewAssert(t, "TestMinisignThirdPartyKey")
+
+	// 取自 aead.dev/minisign 的测试数据, 使用 minisign 默认的 scrypt 参数加密
+	key := "untrusted comment: minisign encrypted secret key\n" +
+		"RWRTY0Iytaz5znJmUO5kBt5xVkvpBl+29A7pZH86phD4h8vD3V8AAAACAAAAAAAAAEAAAAAA9vH9EcS6NdXNIEGhYGoqG1CiL4aptyJreJ4IfuT4+1h+OgVaY/vi0HsbCP0Y6n/wcy0AN0wOXmVDPP33jZqv82YCj2fH+/6MRuAfzNQYoLvc3sH/8bIwqdfpKIjDRZhvqRf063RFYoI=\n"
+	sk, err := ParseMinisignPrivateKey([]byte(key), "correct horse battery staple")
+	assert.IsNil(err)
+	assert.Equal("RWRQhGcHOBlzw4CoKyugkk4ioDfoxlXxC9LBx+VNhJ3w9w+cAxgvPsuo", sk.Public().Base64())
+	assert.Equal("C373193807678450", sk.Public().KeyIDHex())
+
+	sig, err := ParseMinisignSignature("untrusted comment: signature from minisign secret key\n" +
+		"RWRQhGcHOBlzwxrJCyuC+rJfHSfyRKRxkuwa3JJ0bWEs7RHjL1OUmqnTr+V1B9JzFuJIH/ybR2Eus9oEZKt9RbitpF/L4D3+5wg=\n" +
+		"trusted comment: timestamp:1614549543\tfile:message.txt\n" +
+		"P/722+ynQ+tIy0qadFHwLx5MsyNz/jDKJkDWQj4dDD2OKnVte8m/M14mwPE/1NMwzShPMSBhMXqZGdbe+UZjDg==\n")
+	assert.IsNil(err)
+	assert.IsNil(sk.Public().Verify(strings.NewReader("Hello World!\n"), sig))
+}
+
+func TestMinisignFile(t *testing.T) {
+	assert := internal.NewAssert(t, "TestMinisignFile")
+
+	pk, sk, err := GenerateMinisignKey()
+	assert.IsNil(err)
+
+	dest := filepath.Join(t.TempDir(), "release.tgz")
+	assert.IsNil(TarGzip(dest, "testdata/date.txt"))
+
+	_, err = sk.SignFile(dest, "")
+	assert.IsNil(err)
+	assert.Equal(true, IsExist(dest+MinisignSigExt))
+
+	sig, err := pk.VerifyFile(dest)
+	assert.IsNil(err)
+	assert.Equal(true, strings.Contains(sig.TrustedComment, "\tfile:release.tgz\thashed"))
+
+	// 篡改文件
+	f, err := os.OpenFile(dest, os.O_APPEND|os.O_WRONLY, 0644)
+	assert.IsNil(err)
+	_, err = f.WriteString("x")
+	assert.IsNil(err)
+	f.Close()
+	_, err = pk.VerifyFile(dest)
+	assert.Equal(true, errors.Is(err, ErrInvalidSignature))
+}
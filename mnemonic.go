@@ -0,0 +1,239 @@
+package crab
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// MnemonicLanguage 助记词词表
+type MnemonicLanguage string
+
+const (
+	// MnemonicEnglish BIP39 英文词表
+	MnemonicEnglish MnemonicLanguage = "english"
+	// MnemonicChinese BIP39 简体中文词表
+	MnemonicChinese MnemonicLanguage = "chinese"
+)
+
+// MnemonicWordError 助记词中无法识别的单词
+type MnemonicWordError struct {
+	// Position 单词位置, 从 1 开始
+	Position int
+	Word     string
+	// Suggestions 拼写相近的候选单词
+	Suggestions []string
+}
+
+func (e *MnemonicWordError) Error() string {
+	msg := fmt.Sprintf("%v: unknown word %q at position %d", ErrInvalidMnemonic, e.Word, e.Position)
+	if len(e.Suggestions) > 0 {
+		msg += ", did you mean " + strings.Join(e.Suggestions, " / ")
+	}
+	return msg
+}
+
+func (e *MnemonicWordError) Unwrap() error {
+	return ErrInvalidMnemonic
+}
+
+type mnemonicWordlist struct {
+	words []string
+	index map[string]int
+}
+
+var (
+	mnemonicOnce      sync.Once
+	mnemonicWordlists map[MnemonicLanguage]*mnemonicWordlist
+)
+
+func getMnemonicWordlist(lang MnemonicLanguage) (*mnemonicWordlist, error) {
+	mnemonicOnce.Do(func() {
+		mnemonicWordlists = map[MnemonicLanguage]*mnemonicWordlist{
+			MnemonicEnglish: newMnemonicWordlist(bip39English),
+			MnemonicChinese: newMnemonicWordlist(bip39ChineseSimplified),
+		}
+	})
+	wl, ok := mnemonicWordlists[lang]
+	if !ok {
+		return nil, fmt.Errorf("%w: mnemonic language %q", ErrUnsupportedAlgorithm, lang)
+	}
+	return wl, nil
+}
+
+func newMnemonicWordlist(words []string) *mnemonicWordlist {
+	wl := &mnemonicWordlist{words: words, index: make(map[string]int, len(words))}
+	for i, w := range words {
+		wl.index[w] = i
+	}
+	return wl
+}
+
+// MnemonicEncode 把字节编码为 BIP39 英文助记词
+// 长度必须是 4 的倍数且在 16 到 1024 字节之间, 16 到 32 字节时与 BIP39 完全一致
+func MnemonicEncode(data []byte) (string, error) {
+	return MnemonicEncodeWithLanguage(data, MnemonicEnglish)
+}
+
+// MnemonicEncodeWithLanguage 使用指定词表编码助记词
+// 每 4 个字节追加 1 位 SHA-256 校验和, 每 11 位对应一个单词
+func MnemonicEncodeWithLanguage(data []byte, lang MnemonicLanguage) (string, error) {
+	wl, err := getMnemonicWordlist(lang)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < 16 || len(data) > 1024 || len(data)%4 != 0 {
+		return "", fmt.Errorf("%w: mnemonic data must be 16-1024 bytes and a multiple of 4, got %d", ErrInvalidKeySize, len(data))
+	}
+
+	checksum := sha256.Sum256(data)
+	csBits := len(data) / 4
+	bits := make([]byte, 0, len(data)*8+csBits)
+	bits = appendBits(bits, data, len(data)*8)
+	bits = appendBits(bits, checksum[:], csBits)
+
+	words := make([]string, len(bits)/11)
+	for i := range words {
+		idx := 0
+		for _, b := range bits[i*11 : (i+1)*11] {
+			idx = idx<<1 | int(b)
+		}
+		words[i] = wl.words[idx]
+	}
+	return strings.Join(words, " "), nil
+}
+
+// MnemonicDecode 解码助记词, 自动识别英文或中文词表
+// 英文单词不区分大小写, 可以只写前 4 个字母; 中文可以不加空格
+// 无法识别的单词返回 *MnemonicWordError 并给出拼写相近的候选单词
+func MnemonicDecode(mnemonic string) ([]byte, error) {
+	lang := MnemonicEnglish
+	for _, r := range mnemonic {
+		if r >= 0x4e00 && r <= 0x9fff {
+			lang = MnemonicChinese
+			break
+		}
+	}
+	return MnemonicDecodeWithLanguage(mnemonic, lang)
+}
+
+// MnemonicDecodeWithLanguage 使用指定词表解码助记词并校验 checksum
+func MnemonicDecodeWithLanguage(mnemonic string, lang MnemonicLanguage) ([]byte, error) {
+	wl, err := getMnemonicWordlist(lang)
+	if err != nil {
+		return nil, err
+	}
+
+	words := strings.Fields(mnemonic)
+	if lang == MnemonicChinese && len(words) == 1 && utf8.RuneCountInString(words[0]) > 1 {
+		words = strings.Split(words[0], "")
+	}
+	if len(words) < 12 || len(words) > 768 || len(words)%3 != 0 {
+		return nil, fmt.Errorf("%w: word count must be a multiple of 3 between 12 and 768, got %d", ErrInvalidMnemonic, len(words))
+	}
+
+	bits := make([]byte, 0, len(words)*11)
+	for i, w := range words {
+		idx, ok := wl.lookup(w)
+		if !ok {
+			return nil, &MnemonicWordError{Position: i + 1, Word: w, Suggestions: wl.suggest(w)}
+		}
+		for b := 10; b >= 0; b-- {
+			bits = append(bits, byte(idx>>b&1))
+		}
+	}
+
+	// 总位数 = 33 * ENT / 32
+	entBits := len(bits) * 32 / 33
+	data := make([]byte, entBits/8)
+	for i, b := range bits[:entBits] {
+		data[i/8] |= b << (7 - i%8)
+	}
+
+	checksum := sha256.Sum256(data)
+	want := appendBits(nil, checksum[:], len(bits)-entBits)
+	for i, b := range bits[entBits:] {
+		if b != want[i] {
+			return nil, fmt.Errorf("%w: checksum mismatch", ErrInvalidMnemonic)
+		}
+	}
+	return data, nil
+}
+
+// IsMnemonic 判断是否是合法的助记词
+func IsMnemonic(mnemonic string) bool {
+	_, err := MnemonicDecode(mnemonic)
+	return err == nil
+}
+
+func (wl *mnemonicWordlist) lookup(word string) (int, bool) {
+	word = strings.ToLower(word)
+	if idx, ok := wl.index[word]; ok {
+		return idx, true
+	}
+	// BIP39 英文单词前 4 个字母唯一
+	if utf8.RuneCountInString(word) == 4 {
+		for i, w := range wl.words {
+			if strings.HasPrefix(w, word) {
+				return i, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// suggest 返回编辑距离不超过 2 的候选单词, 最多 3 个
+func (wl *mnemonicWordlist) suggest(word string) []string {
+	word = strings.ToLower(word)
+	type candidate struct {
+		word     string
+		distance int
+	}
+	var candidates []candidate
+	for _, w := range wl.words {
+		if d := levenshtein(word, w); d <= 2 {
+			candidates = append(candidates, candidate{w, d})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].distance < candidates[j].distance
+	})
+
+	var out []string
+	for i := 0; i < len(candidates) && i < 3; i++ {
+		out = append(out, candidates[i].word)
+	}
+	return out
+}
+
+// appendBits 按大端顺序追加 data 的前 n 位
+func appendBits(bits, data []byte, n int) []byte {
+	for i := 0; i < n; i++ {
+		bits = append(bits, data[i/8]>>(7-i%8)&1)
+	}
+	return bits
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
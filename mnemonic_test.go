@@ -0,0 +1,89 @@
+package crab
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/serialt/crab/internal"
+)
+
+func TestMnemonicVectors(t *testing.T) {
+	assert := internal.NewAssert(t, "TestMnemonicVectors")
+
+	// https://github.com/trezor/python-mnemonic/blob/master/vectors.json
+	vectors := []struct {
+		entropy  string
+		mnemonic string
+	}{
+		{"00000000000000000000000000000000", "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"},
+		{"7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f", "legal winner thank year wave sausage worth useful legal winner thank yellow"},
+		{"80808080808080808080808080808080", "letter advice cage absurd amount doctor acoustic avoid letter advice cage above"},
+		{"ffffffffffffffffffffffffffffffff", "zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong"},
+		{"9e885d952ad362caeb4efe34a8e91bd2", "ozone drill grab fiber curtain grace pudding thank cruise elder eight picnic"},
+		{"0000000000000000000000000000000000000000000000000000000000000000", "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon art"},
+	}
+	for _, v := range vectors {
+		data, _ := hex.DecodeString(v.entropy)
+		got, err := MnemonicEncode(data)
+		assert.IsNil(err)
+		assert.Equal(v.mnemonic, got)
+
+		decoded, err := MnemonicDecode(v.mnemonic)
+		assert.IsNil(err)
+		assert.Equal(data, decoded)
+	}
+}
+
+func TestMnemonicChinese(t *testing.T) {
+	assert := internal.NewAssert(t, "TestMnemonicChinese")
+
+	data, _ := hex.DecodeString("00000000000000000000000000000000")
+	got, err := MnemonicEncodeWithLanguage(data, MnemonicChinese)
+	assert.IsNil(err)
+	assert.Equal("的 的 的 的 的 的 的 的 的 的 的 在", got)
+
+	key := GenerateKey(32)
+	words, err := MnemonicEncodeWithLanguage(key, MnemonicChinese)
+	assert.IsNil(err)
+	decoded, err := MnemonicDecode(words)
+	assert.IsNil(err)
+	assert.Equal(key, decoded)
+
+	// 不加空格
+	decoded, err = MnemonicDecode(strings.ReplaceAll(words, " ", ""))
+	assert.IsNil(err)
+	assert.Equal(key, decoded)
+
+	_, err = MnemonicEncodeWithLanguage(key, "klingon")
+	assert.Equal(true, errors.Is(err, ErrUnsupportedAlgorithm))
+}
+
+func TestMnemonicTypo(t *testing.T) {
+	assert := internal.NewAssert(t, "TestMnemonicTypo")
+
+	mnemonic := "legal winner thank year wave sausage worth useful legal winner thank yellow"
+
+	// 大小写和前 4 个字母缩写
+	decoded, err := MnemonicDecode("LEGA winn than year wave saus wort usef legal winner thank yell")
+	assert.IsNil(err)
+	assert.Equal(true, IsMnemonic(mnemonic))
+	data, _ := MnemonicDecode(mnemonic)
+	assert.Equal(data, decoded)
+
+	_, err = MnemonicDecode(strings.Replace(mnemonic, "sausage", "sausgae", 1))
+	var wordErr *MnemonicWordError
+	assert.Equal(true, errors.As(err, &wordErr))
+	assert.Equal(6, wordErr.Position)
+	assert.Equal("sausage", wordErr.Suggestions[0])
+	assert.Equal(true, errors.Is(err, ErrInvalidMnemonic))
+
+	// 单词合法但顺序错误, 由 checksum 发现
+	_, err = MnemonicDecode(strings.Replace(mnemonic, "wave sausage", "sausage wave", 1))
+	assert.Equal(true, errors.Is(err, ErrInvalidMnemonic))
+	assert.Equal(false, IsMnemonic("abandon abandon"))
+
+	_, err = MnemonicEncode([]byte("short"))
+	assert.Equal(true, errors.Is(err, ErrInvalidKeySize))
+}
@@ -0,0 +1,253 @@
+package crab
+
+import (
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OTPAlgorithm HOTP/TOTP 使用的 HMAC 算法, 取值与 otpauth URI 中的 algorithm 参数一致
+type OTPAlgorithm string
+
+const (
+	OTPSHA1   OTPAlgorithm = "SHA1"
+	OTPSHA256 OTPAlgorithm = "SHA256"
+	OTPSHA512 OTPAlgorithm = "SHA512"
+)
+
+const (
+	defaultOTPDigits = 6
+	defaultOTPPeriod = 30 * time.Second
+	// otpSecretSize 生成的 secret 字节数, 与 RFC 4226 推荐的 160 位一致
+	otpSecretSize = 20
+)
+
+var otpBase32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// OTPOpt HOTP/TOTP 参数, 零值为 Google Authenticator 兼容的 SHA1、6 位、30 秒
+type OTPOpt struct {
+	// Algorithm HMAC 算法, 默认 SHA1
+	Algorithm OTPAlgorithm
+	// Digits 验证码位数, 6 到 8, 默认 6
+	Digits int
+	// Period TOTP 时间步长, 默认 30 秒, 按秒取整
+	Period time.Duration
+	// Skew 允许偏差的步数; TOTP 为前后各 Skew 个时间步, HOTP 为向后查找 Skew 个计数器
+	Skew int
+	// Replay 验证码匹配后以对应的计数器 (TOTP 为时间步) 调用, 返回错误时验证失败
+	// 调用方在这里记录已使用的计数器, 计数器已使用时返回 ErrOTPReplayed, 防止验证码被重复使用
+	Replay func(counter uint64) error
+}
+
+// GenerateOTPSecret 生成 160 位随机 secret, 返回不带填充的 base32 编码
+func GenerateOTPSecret() (string, error) {
+	secret := make([]byte, otpSecretSize)
+	if err := randRead(secret); err != nil {
+		return "", err
+	}
+	return otpBase32.EncodeToString(secret), nil
+}
+
+// HOTP 按 RFC 4226 计算 counter 对应的验证码, secret 为 base32 编码
+func HOTP(secret string, counter uint64, opt OTPOpt) (string, error) {
+	key, err := decodeOTPSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	if err := opt.validate(); err != nil {
+		return "", err
+	}
+	return opt.hotp(key, counter), nil
+}
+
+// ValidateHOTP 验证 HOTP 验证码, 从 counter 开始向后查找 opt.Skew 个计数器
+// 验证通过时返回下一次应使用的计数器, 调用方需保存它
+func ValidateHOTP(secret, code string, counter uint64, opt OTPOpt) (uint64, error) {
+	key, err := decodeOTPSecret(secret)
+	if err != nil {
+		return counter, err
+	}
+	if err := opt.validate(); err != nil {
+		return counter, err
+	}
+	for i := 0; i <= opt.Skew; i++ {
+		if opt.match(key, counter+uint64(i), code) {
+			if err := opt.replay(counter + uint64(i)); err != nil {
+				return counter, err
+			}
+			return counter + uint64(i) + 1, nil
+		}
+	}
+	return counter, ErrInvalidOTP
+}
+
+// TOTP 按 RFC 6238 计算 t 时刻的验证码, secret 为 base32 编码
+func TOTP(secret string, t time.Time, opt OTPOpt) (string, error) {
+	return HOTP(secret, opt.timeStep(t), opt)
+}
+
+// ValidateTOTP 用当前时间验证 TOTP 验证码
+func ValidateTOTP(secret, code string, opt OTPOpt) error {
+	return ValidateTOTPAt(secret, code, time.Now(), opt)
+}
+
+// ValidateTOTPAt 验证 t 时刻的 TOTP 验证码, 允许前后各 opt.Skew 个时间步
+func ValidateTOTPAt(secret, code string, t time.Time, opt OTPOpt) error {
+	key, err := decodeOTPSecret(secret)
+	if err != nil {
+		return err
+	}
+	if err := opt.validate(); err != nil {
+		return err
+	}
+	step := opt.timeStep(t)
+	for i := -opt.Skew; i <= opt.Skew; i++ {
+		if i < 0 && step < uint64(-i) {
+			continue
+		}
+		if opt.match(key, step+uint64(i), code) {
+			return opt.replay(step + uint64(i))
+		}
+	}
+	return ErrInvalidOTP
+}
+
+// TOTPURI 生成 otpauth://totp/ 格式的 URI, 生成二维码后可以被认证器应用扫描
+// secret 和 opt 的校验与 TOTP 一致, 不会生成认证器无法使用的 URI
+func TOTPURI(secret, issuer, account string, opt OTPOpt) (string, error) {
+	params, err := opt.uriParams(secret, issuer)
+	if err != nil {
+		return "", err
+	}
+	params.Set("period", strconv.Itoa(int(opt.period()/time.Second)))
+	return otpURI("totp", issuer, account, params), nil
+}
+
+// HOTPURI 生成 otpauth://hotp/ 格式的 URI, counter 为初始计数器
+func HOTPURI(secret, issuer, account string, counter uint64, opt OTPOpt) (string, error) {
+	params, err := opt.uriParams(secret, issuer)
+	if err != nil {
+		return "", err
+	}
+	params.Set("counter", strconv.FormatUint(counter, 10))
+	return otpURI("hotp", issuer, account, params), nil
+}
+
+func (opt OTPOpt) validate() error {
+	if opt.Digits != 0 && (opt.Digits < 6 || opt.Digits > 8) {
+		return fmt.Errorf("invalid otp digits %d", opt.Digits)
+	}
+	if opt.Period != 0 && opt.Period < time.Second {
+		return fmt.Errorf("invalid otp period %s", opt.Period)
+	}
+	if opt.Skew < 0 {
+		return fmt.Errorf("invalid otp skew %d", opt.Skew)
+	}
+	switch opt.algorithm() {
+	case OTPSHA1, OTPSHA256, OTPSHA512:
+		return nil
+	}
+	return fmt.Errorf("%w: otp %q", ErrUnsupportedAlgorithm, opt.Algorithm)
+}
+
+func (opt OTPOpt) algorithm() OTPAlgorithm {
+	if opt.Algorithm == "" {
+		return OTPSHA1
+	}
+	return opt.Algorithm
+}
+
+func (opt OTPOpt) digits() int {
+	if opt.Digits == 0 {
+		return defaultOTPDigits
+	}
+	return opt.Digits
+}
+
+func (opt OTPOpt) period() time.Duration {
+	if opt.Period == 0 {
+		return defaultOTPPeriod
+	}
+	return opt.Period.Truncate(time.Second)
+}
+
+func (opt OTPOpt) timeStep(t time.Time) uint64 {
+	return uint64(t.Unix()) / uint64(opt.period()/time.Second)
+}
+
+func (opt OTPOpt) replay(counter uint64) error {
+	if opt.Replay == nil {
+		return nil
+	}
+	return opt.Replay(counter)
+}
+
+// hotp RFC 4226 的动态截断
+func (opt OTPOpt) hotp(key []byte, counter uint64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+
+	var mac string
+	switch opt.algorithm() {
+	case OTPSHA256:
+		mac = HmacSha256(string(msg[:]), string(key))
+	case OTPSHA512:
+		mac = HmacSha512(string(msg[:]), string(key))
+	default:
+		mac = HmacSha1(string(msg[:]), string(key))
+	}
+	sum, _ := hex.DecodeString(mac)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < opt.digits(); i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", opt.digits(), value%mod)
+}
+
+func (opt OTPOpt) match(key []byte, counter uint64, code string) bool {
+	return subtle.ConstantTimeCompare([]byte(opt.hotp(key, counter)), []byte(code)) == 1
+}
+
+func (opt OTPOpt) uriParams(secret, issuer string) (url.Values, error) {
+	if _, err := decodeOTPSecret(secret); err != nil {
+		return nil, err
+	}
+	if err := opt.validate(); err != nil {
+		return nil, err
+	}
+	params := url.Values{}
+	params.Set("secret", secret)
+	if issuer != "" {
+		params.Set("issuer", issuer)
+	}
+	params.Set("algorithm", string(opt.algorithm()))
+	params.Set("digits", strconv.Itoa(opt.digits()))
+	return params, nil
+}
+
+func otpURI(typ, issuer, account string, params url.Values) string {
+	label := url.PathEscape(account)
+	if issuer != "" {
+		label = url.PathEscape(issuer) + ":" + label
+	}
+	return "otpauth://" + typ + "/" + label + "?" + params.Encode()
+}
+
+// decodeOTPSecret 解码 base32 secret, 忽略大小写、空格和填充
+func decodeOTPSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.ReplaceAll(secret, " ", ""))
+	key, err := otpBase32.DecodeString(strings.TrimRight(secret, "="))
+	if err != nil || len(key) == 0 {
+		return nil, fmt.Errorf("%w: otp secret must be base32", ErrInvalidKey)
+	}
+	return key, nil
+}
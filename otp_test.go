@@ -0,0 +1,135 @@
+package crab
+
+import (
+	"encoding/base32"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/serialt/crab/internal"
+)
+
+func TestHOTP(t *testing.T) {
+	assert := internal.NewAssert(t, "TestHOTP")
+
+	// RFC 4226 附录 D
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	expected := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+	for i, code := range expected {
+		got, err := HOTP(secret, uint64(i), OTPOpt{})
+		assert.IsNil(err)
+		assert.Equal(code, got)
+	}
+
+	next, err := ValidateHOTP(secret, "969429", 1, OTPOpt{Skew: 2})
+	assert.IsNil(err)
+	assert.Equal(uint64(4), next)
+
+	next, err = ValidateHOTP(secret, "969429", 0, OTPOpt{Skew: 2})
+	assert.Equal(true, errors.Is(err, ErrInvalidOTP))
+	assert.Equal(uint64(0), next)
+
+	_, err = HOTP("not base32!", 0, OTPOpt{})
+	assert.Equal(true, errors.Is(err, ErrInvalidKey))
+	_, err = HOTP(secret, 0, OTPOpt{Digits: 4})
+	assert.IsNotNil(err)
+	_, err = HOTP(secret, 0, OTPOpt{Algorithm: "MD5"})
+	assert.Equal(true, errors.Is(err, ErrUnsupportedAlgorithm))
+}
+
+func TestTOTP(t *testing.T) {
+	assert := internal.NewAssert(t, "TestTOTP")
+
+	// RFC 6238 附录 B
+	secrets := map[OTPAlgorithm]string{
+		OTPSHA1:   base32.StdEncoding.EncodeToString([]byte("12345678901234567890")),
+		OTPSHA256: base32.StdEncoding.EncodeToString([]byte("12345678901234567890123456789012")),
+		OTPSHA512: base32.StdEncoding.EncodeToString([]byte(strings.Repeat("1234567890", 6) + "1234")),
+	}
+	cases := []struct {
+		unix int64
+		alg  OTPAlgorithm
+		code string
+	}{
+		{59, OTPSHA1, "94287082"},
+		{59, OTPSHA256, "46119246"},
+		{59, OTPSHA512, "90693936"},
+		{1111111109, OTPSHA1, "07081804"},
+		{1111111109, OTPSHA256, "68084774"},
+		{1111111109, OTPSHA512, "25091201"},
+		{20000000000, OTPSHA1, "65353130"},
+		{20000000000, OTPSHA256, "77737706"},
+		{20000000000, OTPSHA512, "47863826"},
+	}
+	for _, c := range cases {
+		opt := OTPOpt{Algorithm: c.alg, Digits: 8}
+		code, err := TOTP(secrets[c.alg], time.Unix(c.unix, 0), opt)
+		assert.IsNil(err)
+		assert.Equal(c.code, code)
+		assert.IsNil(ValidateTOTPAt(secrets[c.alg], c.code, time.Unix(c.unix, 0), opt))
+	}
+
+	secret, err := GenerateOTPSecret()
+	assert.IsNil(err)
+	assert.Equal(32, len(secret))
+
+	now := time.Unix(1700000000, 0)
+	code, err := TOTP(secret, now.Add(-30*time.Second), OTPOpt{})
+	assert.IsNil(err)
+	assert.Equal(true, errors.Is(ValidateTOTPAt(secret, code, now, OTPOpt{}), ErrInvalidOTP))
+	assert.IsNil(ValidateTOTPAt(secret, code, now, OTPOpt{Skew: 1}))
+
+	// 记录已使用的时间步, 同一个验证码不能再次使用
+	used := map[uint64]bool{}
+	opt := OTPOpt{Skew: 1, Replay: func(counter uint64) error {
+		if used[counter] {
+			return ErrOTPReplayed
+		}
+		used[counter] = true
+		return nil
+	}}
+	assert.IsNil(ValidateTOTPAt(secret, code, now, opt))
+	assert.Equal(true, errors.Is(ValidateTOTPAt(secret, code, now, opt), ErrOTPReplayed))
+	assert.IsNil(ValidateTOTP(secret, mustTOTP(t, secret, time.Now()), opt))
+}
+
+func TestOTPURI(t *testing.T) {
+	assert := internal.NewAssert(t, "TestOTPURI")
+
+	uri, err := TOTPURI("JBSWY3DPEHPK3PXP", "Example Co", "alice@example.com", OTPOpt{})
+	assert.IsNil(err)
+	u, err := url.Parse(uri)
+	assert.IsNil(err)
+	assert.Equal("otpauth", u.Scheme)
+	assert.Equal("totp", u.Host)
+	assert.Equal("/Example Co:alice@example.com", u.Path)
+	assert.Equal("JBSWY3DPEHPK3PXP", u.Query().Get("secret"))
+	assert.Equal("Example Co", u.Query().Get("issuer"))
+	assert.Equal("SHA1", u.Query().Get("algorithm"))
+	assert.Equal("6", u.Query().Get("digits"))
+	assert.Equal("30", u.Query().Get("period"))
+
+	uri, err = HOTPURI("JBSWY3DPEHPK3PXP", "", "bob", 7, OTPOpt{Algorithm: OTPSHA256, Digits: 8})
+	assert.IsNil(err)
+	assert.Equal("otpauth://hotp/bob?algorithm=SHA256&counter=7&digits=8&secret=JBSWY3DPEHPK3PXP", uri)
+
+	// 与生成验证码使用相同的校验
+	_, err = TOTPURI("JBSWY3DPEHPK3PXP", "", "bob", OTPOpt{Digits: 10})
+	assert.IsNotNil(err)
+	_, err = TOTPURI("JBSWY3DPEHPK3PXP", "", "bob", OTPOpt{Algorithm: "MD5"})
+	assert.Equal(true, errors.Is(err, ErrUnsupportedAlgorithm))
+	_, err = HOTPURI("JBSWY3DPEHPK3PXP", "", "bob", 0, OTPOpt{Algorithm: "sha256"})
+	assert.Equal(true, errors.Is(err, ErrUnsupportedAlgorithm))
+	_, err = HOTPURI("not base32!", "", "bob", 0, OTPOpt{})
+	assert.Equal(true, errors.Is(err, ErrInvalidKey))
+}
+
+func mustTOTP(t *testing.T, secret string, now time.Time) string {
+	code, err := TOTP(secret, now, OTPOpt{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
@@ -0,0 +1,64 @@
+package crab
+
+import (
+	"crypto/rand"
+	"io"
+	"math/big"
+	"sync"
+)
+
+var (
+	randMu     sync.RWMutex
+	randReader io.Reader = rand.Reader
+)
+
+// SetRandReader 设置加解密、密钥生成和 Shamir 等函数使用的随机数来源, 传入 nil 恢复为 crypto/rand
+// 只应在测试中替换为可复现的来源, 例如 math/rand/v2 的 ChaCha8。
+// 注意 Go 标准库生成 RSA 密钥时总是使用系统随机数, 不受这里影响
+func SetRandReader(r io.Reader) {
+	randMu.Lock()
+	defer randMu.Unlock()
+	if r == nil {
+		r = rand.Reader
+	}
+	randReader = r
+}
+
+// RandReader 返回当前使用的随机数来源
+func RandReader() io.Reader {
+	randMu.RLock()
+	defer randMu.RUnlock()
+	return randReader
+}
+
+// randRead 从当前随机数来源读满 b
+func randRead(b []byte) error {
+	_, err := io.ReadFull(RandReader(), b)
+	return err
+}
+
+// randIntn 返回 [0, n) 内均匀分布的随机数
+func randIntn(n int) (int, error) {
+	v, err := rand.Int(RandReader(), big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(v.Int64()), nil
+}
+
+// randPerm 返回 [0, n) 的随机排列
+func randPerm(n int) ([]int, error) {
+	perm := make([]int, n)
+	for i := range perm {
+		perm[i] = i
+	}
+	// Fisher-Yates 洗牌
+	for i := n - 1; i > 0; i-- {
+		j, err := randIntn(i + 1)
+		if err != nil {
+			return nil, err
+		}
+		perm[i], perm[j] = perm[j], perm[i]
+	}
+	return perm, nil
+}
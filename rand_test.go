@@ -0,0 +1,55 @@
+package crab
+
+import (
+	"bytes"
+	mathrand "math/rand/v2"
+	"testing"
+
+	"github.com/serialt/crab/internal"
+)
+
+func withSeededRand(t *testing.T, seed byte) {
+	t.Helper()
+	SetRandReader(mathrand.NewChaCha8([32]byte{seed}))
+	t.Cleanup(func() { SetRandReader(nil) })
+}
+
+func TestSetRandReader(t *testing.T) {
+	assert := internal.NewAssert(t, "TestSetRandReader")
+
+	run := func() ([]byte, [][]byte, []byte) {
+		withSeededRand(t, 1)
+		key := GenerateKey(32)
+		shares, err := ShamirSplit([]byte("test"), 5, 3)
+		assert.IsNil(err)
+		data, err := AESEncryptGCM([]byte("hello"), key)
+		assert.IsNil(err)
+		return key, shares, data
+	}
+
+	key1, shares1, data1 := run()
+	key2, shares2, data2 := run()
+	assert.Equal(key1, key2)
+	assert.Equal(shares1, shares2)
+	assert.Equal(data1, data2)
+
+	withSeededRand(t, 2)
+	key3 := GenerateKey(32)
+	assert.Equal(false, bytes.Equal(key1, key3))
+
+	SetRandReader(nil)
+	assert.Equal(false, bytes.Equal(GenerateKey(32), GenerateKey(32)))
+}
+
+func TestRandPerm(t *testing.T) {
+	assert := internal.NewAssert(t, "TestRandPerm")
+
+	perm, err := randPerm(255)
+	assert.IsNil(err)
+	seen := map[int]bool{}
+	for _, v := range perm {
+		assert.Equal(true, v >= 0 && v < 255)
+		seen[v] = true
+	}
+	assert.Equal(255, len(seen))
+}
@@ -0,0 +1,300 @@
+package crab
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 请求签名使用的 header 和常量
+const (
+	RequestSignAlgorithm     = "CRAB-HMAC-SHA256"
+	RequestSignDateHeader    = "X-Crab-Date"
+	RequestSignContentHeader = "X-Crab-Content-Sha256"
+	requestSignDateFormat    = "20060102T150405Z"
+	requestSignScope         = "crab_request"
+	// defaultRequestSignTolerance 默认允许的时间偏差, 与 SigV4 一致
+	defaultRequestSignTolerance = 15 * time.Minute
+)
+
+type requestSignKey struct{}
+
+// RequestSigner 类似 AWS SigV4 的请求签名, 用于服务之间的认证
+// 签名内容为 method、path、排序后的 query、选定的 header 和 body 的 sha256,
+// 签名密钥由 secret 和日期派生, 泄露的当日密钥不能用于其他日期
+type RequestSigner struct {
+	accessKeyID string
+	secret      string
+	headers     []string
+	now         func() time.Time
+}
+
+// NewRequestSigner 创建 RequestSigner, headers 为除 host、X-Crab-Date 和 X-Crab-Content-Sha256 外需要签名的 header
+func NewRequestSigner(accessKeyID, secret string, headers ...string) *RequestSigner {
+	return &RequestSigner{
+		accessKeyID: accessKeyID,
+		secret:      secret,
+		headers:     headers,
+		now:         time.Now,
+	}
+}
+
+// Sign 为请求签名, 设置 X-Crab-Date、X-Crab-Content-Sha256 和 Authorization header
+// 会读取 body 计算摘要, 之后 body 被重新设置以便发送
+func (s *RequestSigner) Sign(req *http.Request) error {
+	if s.accessKeyID == "" || s.secret == "" {
+		return fmt.Errorf("%w: access key id and secret", ErrEmptyInput)
+	}
+	body, err := readRequestBody(req, -1)
+	if err != nil {
+		return err
+	}
+
+	t := s.now().UTC()
+	date := t.Format(requestSignDateFormat)
+	req.Header.Set(RequestSignDateHeader, date)
+	req.Header.Set(RequestSignContentHeader, Sha256(string(body)))
+
+	signed := signedHeaderNames(s.headers)
+	scope := date[:8] + "/" + requestSignScope
+	signature := requestSignature(s.secret, date, scope, canonicalRequest(req, requestHost(req), signed))
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		RequestSignAlgorithm, s.accessKeyID, scope, strings.Join(signed, ";"), signature))
+	return nil
+}
+
+// Transport 返回自动签名的 http.RoundTripper, base 为 nil 时使用 http.DefaultTransport
+func (s *RequestSigner) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &signingTransport{signer: s, base: base}
+}
+
+type signingTransport struct {
+	signer *RequestSigner
+	base   http.RoundTripper
+}
+
+// RoundTrip RoundTripper 不能修改原请求, 签名前先复制
+func (t *signingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	clone := req.Clone(req.Context())
+	if err := t.signer.Sign(clone); err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	return t.base.RoundTrip(clone)
+}
+
+// RequestVerifierOpt RequestVerifier 配置
+type RequestVerifierOpt struct {
+	// Lookup 根据 access key id 返回 secret, key 不存在时返回 false, 为 nil 时所有请求都验证失败
+	Lookup func(accessKeyID string) (string, bool)
+	// Tolerance 签名时间与当前时间允许的偏差, 默认 15 分钟
+	Tolerance time.Duration
+	// MaxBodySize 读取的最大 body 字节数, 默认 1 MiB
+	MaxBodySize int64
+}
+
+// RequestVerifier 验证 RequestSigner 签名的请求
+type RequestVerifier struct {
+	lookup    func(string) (string, bool)
+	tolerance time.Duration
+	maxBody   int64
+	now       func() time.Time
+}
+
+// NewRequestVerifier 创建 RequestVerifier
+func NewRequestVerifier(opt RequestVerifierOpt) *RequestVerifier {
+	v := &RequestVerifier{
+		lookup:    opt.Lookup,
+		tolerance: opt.Tolerance,
+		maxBody:   opt.MaxBodySize,
+		now:       time.Now,
+	}
+	if v.tolerance <= 0 {
+		v.tolerance = defaultRequestSignTolerance
+	}
+	if v.maxBody <= 0 {
+		v.maxBody = defaultWebhookMaxBody
+	}
+	return v
+}
+
+// Verify 验证请求签名, 返回签名使用的 access key id; 验证后 body 可以再次读取
+func (v *RequestVerifier) Verify(req *http.Request) (string, error) {
+	if v.lookup == nil {
+		return "", fmt.Errorf("%w: no access key lookup configured", ErrInvalidSignature)
+	}
+	algorithm, params, _ := strings.Cut(req.Header.Get("Authorization"), " ")
+	if algorithm != RequestSignAlgorithm {
+		return "", fmt.Errorf("%w: missing authorization", ErrInvalidSignature)
+	}
+	fields := map[string]string{}
+	for _, part := range strings.Split(params, ",") {
+		if key, value, ok := strings.Cut(strings.TrimSpace(part), "="); ok {
+			fields[key] = value
+		}
+	}
+	accessKeyID, scope, ok := strings.Cut(fields["Credential"], "/")
+	if !ok || fields["SignedHeaders"] == "" || fields["Signature"] == "" {
+		return "", fmt.Errorf("%w: malformed authorization", ErrInvalidSignature)
+	}
+
+	date := req.Header.Get(RequestSignDateHeader)
+	t, err := time.Parse(requestSignDateFormat, date)
+	if err != nil || scope != date[:8]+"/"+requestSignScope {
+		return "", fmt.Errorf("%w: malformed date or scope", ErrInvalidSignature)
+	}
+	if age := v.now().Sub(t); age > v.tolerance || age < -v.tolerance {
+		return "", fmt.Errorf("%w: request date is %s away", ErrSignatureExpired, age.Abs())
+	}
+
+	signed := strings.Split(fields["SignedHeaders"], ";")
+	for _, required := range []string{"host", strings.ToLower(RequestSignDateHeader), strings.ToLower(RequestSignContentHeader)} {
+		if !slices.Contains(signed, required) {
+			return "", fmt.Errorf("%w: %s is not signed", ErrInvalidSignature, required)
+		}
+	}
+
+	secret, ok := v.lookup(accessKeyID)
+	if !ok {
+		return "", fmt.Errorf("%w: unknown access key %s", ErrInvalidSignature, accessKeyID)
+	}
+	body, err := readRequestBody(req, v.maxBody)
+	if err != nil {
+		return "", err
+	}
+	if !hmac.Equal([]byte(Sha256(string(body))), []byte(req.Header.Get(RequestSignContentHeader))) {
+		return "", fmt.Errorf("%w: body hash mismatch", ErrInvalidSignature)
+	}
+
+	expected, _ := hex.DecodeString(requestSignature(secret, date, scope, canonicalRequest(req, req.Host, signed)))
+	got, err := hex.DecodeString(fields["Signature"])
+	if err != nil || !hmac.Equal(expected, got) {
+		return "", ErrInvalidSignature
+	}
+	return accessKeyID, nil
+}
+
+// Middleware 验证请求签名的 net/http 中间件, 验证失败时返回 401
+// 验证通过后可以用 RequestAccessKeyID 从请求的 context 中取得 access key id
+func (v *RequestVerifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accessKeyID, err := v.Verify(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestSignKey{}, accessKeyID)))
+	})
+}
+
+// RequestAccessKeyID 返回 Middleware 验证通过的 access key id
+func RequestAccessKeyID(ctx context.Context) (string, bool) {
+	accessKeyID, ok := ctx.Value(requestSignKey{}).(string)
+	return accessKeyID, ok
+}
+
+// requestSignature 用 secret 和日期派生签名密钥, 对规范化请求签名
+func requestSignature(secret, date, scope, canonical string) string {
+	key := hmacSha256Raw(date[:8], "CRAB"+secret)
+	key = hmacSha256Raw(requestSignScope, key)
+	stringToSign := strings.Join([]string{RequestSignAlgorithm, date, scope, Sha256(canonical)}, "\n")
+	return HmacSha256(stringToSign, key)
+}
+
+func hmacSha256Raw(data, key string) string {
+	raw, _ := hex.DecodeString(HmacSha256(data, key))
+	return string(raw)
+}
+
+// canonicalRequest method \n path \n query \n headers \n signed headers \n body sha256
+// 多值 header 的所有值按顺序用逗号连接, 追加的值也会使签名失效
+func canonicalRequest(req *http.Request, host string, signed []string) string {
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	query := req.URL.Query()
+	var pairs []string
+	for key, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, url.QueryEscape(key)+"="+url.QueryEscape(value))
+		}
+	}
+	sort.Strings(pairs)
+
+	var headers strings.Builder
+	for _, name := range signed {
+		values := req.Header.Values(name)
+		if name == "host" {
+			values = []string{host}
+		}
+		var trimmed []string
+		for _, value := range values {
+			trimmed = append(trimmed, strings.Join(strings.Fields(value), " "))
+		}
+		headers.WriteString(name + ":" + strings.Join(trimmed, ",") + "\n")
+	}
+
+	return strings.Join([]string{
+		req.Method,
+		path,
+		strings.Join(pairs, "&"),
+		headers.String(),
+		strings.Join(signed, ";"),
+		req.Header.Get(RequestSignContentHeader),
+	}, "\n")
+}
+
+// signedHeaderNames 返回小写、排序、去重后的签名 header
+func signedHeaderNames(extra []string) []string {
+	names := []string{"host", strings.ToLower(RequestSignDateHeader), strings.ToLower(RequestSignContentHeader)}
+	for _, name := range extra {
+		names = append(names, strings.ToLower(name))
+	}
+	sort.Strings(names)
+	return SliceUnique(names)
+}
+
+func requestHost(req *http.Request) string {
+	if req.Host != "" {
+		return req.Host
+	}
+	return req.URL.Host
+}
+
+// readRequestBody 读取并重新设置 body, max >= 0 时限制读取的字节数
+func readRequestBody(req *http.Request, max int64) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	r := io.Reader(req.Body)
+	if max >= 0 {
+		r = io.LimitReader(req.Body, max+1)
+	}
+	body, err := io.ReadAll(r)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if max >= 0 && int64(len(body)) > max {
+		return nil, fmt.Errorf("%w: body exceeds %d bytes", ErrBodyTooLarge, max)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+	return body, nil
+}
@@ -0,0 +1,105 @@
+package crab
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/serialt/crab/internal"
+)
+
+func TestRequestSigner(t *testing.T) {
+	assert := internal.NewAssert(t, "TestRequestSigner")
+
+	signer := NewRequestSigner("AKID", "secret", "Content-Type")
+	verifier := NewRequestVerifier(RequestVerifierOpt{Lookup: func(id string) (string, bool) {
+		return "secret", id == "AKID"
+	}})
+
+	newReq := func() *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "http://api.example.com/v1/items?b=2&a=1&a=0", strings.NewReader(`{"name":"crab"}`))
+		req.Header.Set("Content-Type", "application/json")
+		return req
+	}
+
+	req := newReq()
+	assert.IsNil(signer.Sign(req))
+	assert.Equal(true, strings.HasPrefix(req.Header.Get("Authorization"), "CRAB-HMAC-SHA256 Credential=AKID/"))
+	assert.Equal(true, strings.Contains(req.Header.Get("Authorization"), "SignedHeaders=content-type;host;x-crab-content-sha256;x-crab-date,"))
+	accessKeyID, err := verifier.Verify(req)
+	assert.IsNil(err)
+	assert.Equal("AKID", accessKeyID)
+	body, _ := io.ReadAll(req.Body)
+	assert.Equal(`{"name":"crab"}`, string(body))
+
+	tamper := map[string]func(*http.Request){
+		"body":    func(r *http.Request) { r.Body = io.NopCloser(strings.NewReader(`{"name":"fish"}`)) },
+		"query":   func(r *http.Request) { r.URL.RawQuery = "a=1&b=3" },
+		"method":  func(r *http.Request) { r.Method = http.MethodPut },
+		"path":    func(r *http.Request) { r.URL.Path = "/v1/admin" },
+		"header":  func(r *http.Request) { r.Header.Set("Content-Type", "text/plain") },
+		"values":  func(r *http.Request) { r.Header.Add("Content-Type", "text/plain") },
+		"host":    func(r *http.Request) { r.Host = "evil.example.com" },
+		"missing": func(r *http.Request) { r.Header.Del("Authorization") },
+	}
+	for name, fn := range tamper {
+		req := newReq()
+		assert.IsNil(signer.Sign(req))
+		fn(req)
+		_, err := verifier.Verify(req)
+		if !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("tampered %s: %v", name, err)
+		}
+	}
+
+	req = newReq()
+	assert.IsNil(NewRequestSigner("OTHER", "secret").Sign(req))
+	_, err = verifier.Verify(req)
+	assert.Equal(true, errors.Is(err, ErrInvalidSignature))
+
+	req = newReq()
+	signer.now = func() time.Time { return time.Now().Add(-time.Hour) }
+	assert.IsNil(signer.Sign(req))
+	_, err = verifier.Verify(req)
+	assert.Equal(true, errors.Is(err, ErrSignatureExpired))
+
+	assert.IsNotNil(NewRequestSigner("", "").Sign(newReq()))
+
+	// 没有配置 Lookup 时所有请求都验证失败
+	req = newReq()
+	signer.now = time.Now
+	assert.IsNil(signer.Sign(req))
+	_, err = NewRequestVerifier(RequestVerifierOpt{}).Verify(req)
+	assert.Equal(true, errors.Is(err, ErrInvalidSignature))
+}
+
+func TestRequestSignerTransport(t *testing.T) {
+	assert := internal.NewAssert(t, "TestRequestSignerTransport")
+
+	verifier := NewRequestVerifier(RequestVerifierOpt{Lookup: func(id string) (string, bool) {
+		return "secret", id == "AKID"
+	}})
+	server := httptest.NewServer(verifier.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accessKeyID, _ := RequestAccessKeyID(r.Context())
+		body, _ := io.ReadAll(r.Body)
+		w.Write([]byte(accessKeyID + ":" + string(body)))
+	})))
+	defer server.Close()
+
+	client := &http.Client{Transport: NewRequestSigner("AKID", "secret").Transport(nil)}
+	resp, err := client.Post(server.URL+"/upload?name=a+b%2Fc", "text/plain", strings.NewReader("hello"))
+	assert.IsNil(err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	assert.Equal("AKID:hello", string(body))
+
+	resp, err = http.Get(server.URL + "/upload")
+	assert.IsNil(err)
+	resp.Body.Close()
+	assert.Equal(http.StatusUnauthorized, resp.StatusCode)
+}
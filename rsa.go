@@ -2,11 +2,11 @@ package crab
 
 import (
 	"bytes"
-	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha256"
 	"crypto/x509"
 	"encoding/pem"
+	"errors"
 	"fmt"
 )
 
@@ -15,7 +15,7 @@ func GenerateRSAKey(bits int) (priKey, pubKey []byte, err error) {
 	priWriter := bytes.NewBuffer([]byte{})
 	pubWriter := bytes.NewBuffer([]byte{})
 
-	privateKey, err := rsa.GenerateKey(rand.Reader, bits)
+	privateKey, err := rsa.GenerateKey(RandReader(), bits)
 	if err != nil {
 		return
 	}
@@ -33,7 +33,7 @@ func GenerateRSAKey(bits int) (priKey, pubKey []byte, err error) {
 	publicKey := privateKey.PublicKey
 	X509PublicKey, err := x509.MarshalPKIXPublicKey(&publicKey)
 	if err != nil {
-		panic(err)
+		return
 	}
 
 	publicBlock := &pem.Block{
@@ -55,12 +55,12 @@ func GenerateRSAKeyWithPwd(passwd []byte, bits int) (priKey, pubKey []byte, err
 	priWriter := bytes.NewBuffer([]byte{})
 	pubWriter := bytes.NewBuffer([]byte{})
 
-	privateKey, err := rsa.GenerateKey(rand.Reader, bits)
+	privateKey, err := rsa.GenerateKey(RandReader(), bits)
 	if err != nil {
 		return
 	}
 	x509PrivateKey := x509.MarshalPKCS1PrivateKey(privateKey)
-	privateBlock, err := x509.EncryptPEMBlock(rand.Reader, "RSA PRIVATE KEY", x509PrivateKey, passwd, x509.PEMCipherAES256)
+	privateBlock, err := x509.EncryptPEMBlock(RandReader(), "RSA PRIVATE KEY", x509PrivateKey, passwd, x509.PEMCipherAES256)
 	if err != nil {
 		return
 	}
@@ -86,45 +86,65 @@ func GenerateRSAKeyWithPwd(passwd []byte, bits int) (priKey, pubKey []byte, err
 func RSAEncryptOAEP(plainText, pubCipherKey []byte) (cipherText []byte, err error) {
 	block, _ := pem.Decode(pubCipherKey)
 	if block == nil {
-		err = fmt.Errorf("failed to parse certificate PEM")
+		err = fmt.Errorf("%w: failed to parse PEM block", ErrInvalidKey)
 		return
 	}
 	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
 	if err != nil {
+		err = fmt.Errorf("%w: %v", ErrInvalidKey, err)
+		return
+	}
+	publickey, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		err = fmt.Errorf("%w: public key is %T, not RSA", ErrUnsupportedAlgorithm, pub)
 		return
 	}
-	publickey := pub.(*rsa.PublicKey)
-	return rsa.EncryptOAEP(sha256.New(), rand.Reader, publickey, plainText, nil)
+	return rsa.EncryptOAEP(sha256.New(), RandReader(), publickey, plainText, nil)
 }
 
 // RSADecryptOAEP 私钥解密
 func RSADecryptOAEP(cipherText, privCipherKey []byte) (plainText []byte, err error) {
 	block, _ := pem.Decode(privCipherKey)
 	if block == nil {
-		err = fmt.Errorf("failed to parse certificate PEM")
+		err = fmt.Errorf("%w: failed to parse PEM block", ErrInvalidKey)
 		return
 	}
 	privateKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
 	if err != nil {
+		err = fmt.Errorf("%w: %v", ErrInvalidKey, err)
 		return
 	}
-	return rsa.DecryptOAEP(sha256.New(), rand.Reader, privateKey, cipherText, nil)
+	return rsaDecryptOAEP(cipherText, privateKey)
 }
 
 // RSADecryptOAEPPwd 私钥解密,带密码
 func RSADecryptOAEPPwd(cipherText, privCipherKey, passwd []byte) (plainText []byte, err error) {
 	block, _ := pem.Decode(privCipherKey)
 	if block == nil {
-		err = fmt.Errorf("failed to parse certificate PEM")
+		err = fmt.Errorf("%w: failed to parse PEM block", ErrInvalidKey)
 		return
 	}
 	data, err := x509.DecryptPEMBlock(block, passwd)
 	if err != nil {
+		if errors.Is(err, x509.IncorrectPasswordError) {
+			err = ErrPasswordMismatch
+		} else {
+			err = fmt.Errorf("%w: %v", ErrInvalidKey, err)
+		}
 		return
 	}
 	privateKey, err := x509.ParsePKCS1PrivateKey(data)
 	if err != nil {
+		err = fmt.Errorf("%w: %v", ErrInvalidKey, err)
 		return
 	}
-	return rsa.DecryptOAEP(sha256.New(), rand.Reader, privateKey, cipherText, nil)
+	return rsaDecryptOAEP(cipherText, privateKey)
+}
+
+func rsaDecryptOAEP(cipherText []byte, privateKey *rsa.PrivateKey) ([]byte, error) {
+	plainText, err := rsa.DecryptOAEP(sha256.New(), RandReader(), privateKey, cipherText, nil)
+	if err != nil {
+		return nil, ErrAuthenticationFailed
+	}
+	return plainText, nil
 }
@@ -0,0 +1,123 @@
+package crab
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// maxCookieSize 浏览器对单个 cookie 的大小限制
+const maxCookieSize = 4096
+
+// SecureCookie 加密、认证并带过期时间的 token, 类似 gorilla/securecookie
+// value 序列化为 JSON 后与 name、过期时间一起用 AES-GCM 加密, 输出为不带填充的 URL 安全 base64,
+// 可以直接放进 cookie 或 URL; name 参与加密, 一个 cookie 的值不能当作另一个 cookie 使用
+type SecureCookie struct {
+	keys [][]byte
+	now  func() time.Time
+}
+
+type secureCookiePayload struct {
+	Name    string          `json:"n"`
+	Expires int64           `json:"e,omitempty"`
+	Value   json.RawMessage `json:"v"`
+}
+
+// NewSecureCookie 创建 SecureCookie, 密钥长度为 16/24/32
+// 轮换密钥时把新密钥放在第一个: 总是用第一个密钥加密, 解密时依次尝试所有密钥
+func NewSecureCookie(keys ...string) (*SecureCookie, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%w: secure cookie keys", ErrEmptyInput)
+	}
+	s := &SecureCookie{now: time.Now}
+	for _, key := range keys {
+		switch len(key) {
+		case 16, 24, 32:
+		default:
+			return nil, fmt.Errorf("%w: %d", ErrInvalidKeySize, len(key))
+		}
+		s.keys = append(s.keys, []byte(key))
+	}
+	return s, nil
+}
+
+// Encode 加密 value, maxAge 后过期, maxAge <= 0 时不过期
+func (s *SecureCookie) Encode(name string, value any, maxAge time.Duration) (string, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	payload := secureCookiePayload{Name: name, Value: data}
+	if maxAge > 0 {
+		payload.Expires = s.now().Add(maxAge).Unix()
+	}
+	plaintext, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := AESEncryptGCM(plaintext, s.keys[0])
+	if err != nil {
+		return "", err
+	}
+	return Encode(EncodingBase64RawURL, ciphertext)
+}
+
+// Decode 解密 token 并把 value 解析到 dst
+// 密钥都不匹配或 name 不一致返回 ErrAuthenticationFailed, 已过期返回 ErrTokenExpired
+func (s *SecureCookie) Decode(name, token string, dst any) error {
+	ciphertext, err := Decode(EncodingBase64RawURL, token)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCiphertext, err)
+	}
+
+	var plaintext []byte
+	for _, key := range s.keys {
+		if plaintext, err = AESDecryptGCM(ciphertext, key); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	var payload secureCookiePayload
+	if err := json.Unmarshal(plaintext, &payload); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCiphertext, err)
+	}
+	if payload.Name != name {
+		return fmt.Errorf("%w: token is for %q", ErrAuthenticationFailed, payload.Name)
+	}
+	if payload.Expires != 0 && s.now().Unix() > payload.Expires {
+		return fmt.Errorf("%w: expired at %s", ErrTokenExpired, time.Unix(payload.Expires, 0).Format(time.RFC3339))
+	}
+	return json.Unmarshal(payload.Value, dst)
+}
+
+// SetCookie 加密 value 后写入 cookie, cookie.Name 作为 name, cookie.MaxAge 秒后过期
+// cookie 的其他属性 (Path、Secure、HttpOnly 等) 由调用方设置
+func (s *SecureCookie) SetCookie(w http.ResponseWriter, cookie *http.Cookie, value any) error {
+	token, err := s.Encode(cookie.Name, value, time.Duration(cookie.MaxAge)*time.Second)
+	if err != nil {
+		return err
+	}
+	c := *cookie
+	c.Value = token
+	if c.MaxAge > 0 {
+		c.Expires = s.now().Add(time.Duration(c.MaxAge) * time.Second)
+	}
+	if len(c.String()) > maxCookieSize {
+		return fmt.Errorf("cookie %s exceeds %d bytes", c.Name, maxCookieSize)
+	}
+	http.SetCookie(w, &c)
+	return nil
+}
+
+// ReadCookie 读取名为 name 的 cookie 并解密到 dst, cookie 不存在时返回 http.ErrNoCookie
+func (s *SecureCookie) ReadCookie(r *http.Request, name string, dst any) error {
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return err
+	}
+	return s.Decode(name, cookie.Value, dst)
+}
@@ -0,0 +1,89 @@
+package crab
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/serialt/crab/internal"
+)
+
+type testSession struct {
+	UserID int    `json:"uid"`
+	Role   string `json:"role"`
+}
+
+func TestSecureCookie(t *testing.T) {
+	assert := internal.NewAssert(t, "TestSecureCookie")
+
+	_, err := NewSecureCookie()
+	assert.Equal(true, errors.Is(err, ErrEmptyInput))
+	_, err = NewSecureCookie("short")
+	assert.Equal(true, errors.Is(err, ErrInvalidKeySize))
+
+	oldKey := strings.Repeat("o", 32)
+	newKey := strings.Repeat("n", 32)
+	old, err := NewSecureCookie(oldKey)
+	assert.IsNil(err)
+	s, err := NewSecureCookie(newKey, oldKey)
+	assert.IsNil(err)
+
+	token, err := s.Encode("session", testSession{UserID: 7, Role: "admin"}, time.Hour)
+	assert.IsNil(err)
+	assert.Equal(true, IsBase64URL(token))
+	assert.Equal(false, strings.ContainsAny(token, "+/="))
+
+	var got testSession
+	assert.IsNil(s.Decode("session", token, &got))
+	assert.Equal(testSession{UserID: 7, Role: "admin"}, got)
+
+	// 轮换期间旧密钥生成的 token 仍然有效, 新 token 旧密钥无法解密
+	token, _ = old.Encode("session", testSession{UserID: 8}, 0)
+	assert.IsNil(s.Decode("session", token, &got))
+	assert.Equal(8, got.UserID)
+	token, _ = s.Encode("session", testSession{UserID: 9}, 0)
+	assert.Equal(true, errors.Is(old.Decode("session", token, &got), ErrAuthenticationFailed))
+
+	err = s.Decode("csrf", token, &got)
+	assert.Equal(true, errors.Is(err, ErrAuthenticationFailed))
+
+	err = s.Decode("session", token[:len(token)-2]+"AA", &got)
+	assert.IsNotNil(err)
+	err = s.Decode("session", "not a token!", &got)
+	assert.Equal(true, errors.Is(err, ErrInvalidCiphertext))
+
+	token, _ = s.Encode("session", testSession{UserID: 10}, time.Minute)
+	s.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	err = s.Decode("session", token, &got)
+	assert.Equal(true, errors.Is(err, ErrTokenExpired))
+}
+
+func TestSecureCookieHTTP(t *testing.T) {
+	assert := internal.NewAssert(t, "TestSecureCookieHTTP")
+
+	s, err := NewSecureCookie(strings.Repeat("k", 16))
+	assert.IsNil(err)
+
+	rec := httptest.NewRecorder()
+	err = s.SetCookie(rec, &http.Cookie{Name: "session", Path: "/", MaxAge: 3600, HttpOnly: true}, testSession{UserID: 1})
+	assert.IsNil(err)
+	cookies := rec.Result().Cookies()
+	assert.Equal(1, len(cookies))
+	assert.Equal(true, cookies[0].HttpOnly)
+	assert.Equal(3600, cookies[0].MaxAge)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookies[0])
+	var got testSession
+	assert.IsNil(s.ReadCookie(req, "session", &got))
+	assert.Equal(1, got.UserID)
+
+	err = s.ReadCookie(httptest.NewRequest(http.MethodGet, "/", nil), "session", &got)
+	assert.Equal(true, errors.Is(err, http.ErrNoCookie))
+
+	err = s.SetCookie(httptest.NewRecorder(), &http.Cookie{Name: "big"}, strings.Repeat("x", maxCookieSize))
+	assert.IsNotNil(err)
+}
@@ -2,11 +2,8 @@
 package crab
 
 import (
-	"crypto/rand"
 	"crypto/subtle"
 	"fmt"
-	mathrand "math/rand"
-	"time"
 )
 
 const (
@@ -33,7 +30,7 @@ func makePolynomial(intercept, degree uint8) (polynomial, error) {
 	p.coefficients[0] = intercept
 
 	// Assign random co-efficients to the polynomial
-	if _, err := rand.Read(p.coefficients[1:]); err != nil {
+	if err := randRead(p.coefficients[1:]); err != nil {
 		return p, err
 	}
 
@@ -137,24 +134,26 @@ func add(a, b uint8) uint8 {
 func ShamirSplit(secret []byte, parts, threshold int) ([][]byte, error) {
 	// Sanity check the input
 	if parts < threshold {
-		return nil, fmt.Errorf("parts cannot be less than threshold")
+		return nil, fmt.Errorf("%w: parts cannot be less than threshold", ErrInvalidThreshold)
 	}
 	if parts > 255 {
-		return nil, fmt.Errorf("parts cannot exceed 255")
+		return nil, fmt.Errorf("%w: parts cannot exceed 255", ErrInvalidThreshold)
 	}
 	if threshold < 2 {
-		return nil, fmt.Errorf("threshold must be at least 2")
+		return nil, fmt.Errorf("%w: threshold must be at least 2", ErrInvalidThreshold)
 	}
 	if threshold > 255 {
-		return nil, fmt.Errorf("threshold cannot exceed 255")
+		return nil, fmt.Errorf("%w: threshold cannot exceed 255", ErrInvalidThreshold)
 	}
 	if len(secret) == 0 {
-		return nil, fmt.Errorf("cannot split an empty secret")
+		return nil, fmt.Errorf("%w: cannot split an empty secret", ErrEmptyInput)
 	}
 
 	// Generate random list of x coordinates
-	mathrand.Seed(time.Now().UnixNano())
-	xCoordinates := mathrand.Perm(255)
+	xCoordinates, err := randPerm(255)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate x coordinates: %w", err)
+	}
 
 	// Allocate the output array, initialize the final byte
 	// of the output with the offset. The representation of each
@@ -194,17 +193,17 @@ func ShamirSplit(secret []byte, parts, threshold int) ([][]byte, error) {
 func ShamirCombine(parts [][]byte) ([]byte, error) {
 	// Verify enough parts provided
 	if len(parts) < 2 {
-		return nil, fmt.Errorf("less than two parts cannot be used to reconstruct the secret")
+		return nil, fmt.Errorf("%w: less than two parts cannot be used to reconstruct the secret", ErrInvalidShares)
 	}
 
 	// Verify the parts are all the same length
 	firstPartLen := len(parts[0])
 	if firstPartLen < 2 {
-		return nil, fmt.Errorf("parts must be at least two bytes")
+		return nil, fmt.Errorf("%w: parts must be at least two bytes", ErrInvalidShares)
 	}
 	for i := 1; i < len(parts); i++ {
 		if len(parts[i]) != firstPartLen {
-			return nil, fmt.Errorf("all parts must be the same length")
+			return nil, fmt.Errorf("%w: all parts must be the same length", ErrInvalidShares)
 		}
 	}
 
@@ -221,7 +220,7 @@ func ShamirCombine(parts [][]byte) ([]byte, error) {
 	for i, part := range parts {
 		samp := part[firstPartLen-1]
 		if exists := checkMap[samp]; exists {
-			return nil, fmt.Errorf("duplicate part detected")
+			return nil, fmt.Errorf("%w: duplicate part detected", ErrInvalidShares)
 		}
 		checkMap[samp] = true
 		x_samples[i] = samp
@@ -0,0 +1,245 @@
+package crab
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/cipher"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// shamirFileMagic 加密文件的文件头
+var shamirFileMagic = []byte("CRABSSF1")
+
+// shamirFileChunkSize 分块加密时每块明文的大小
+const shamirFileChunkSize = 64 * 1024
+
+// ShamirSplitFile 用随机的 AES-256-GCM 密钥加密文件, 只对密钥做 Shamir 拆分
+// 在 destDir 下写入加密文件 <name>.enc 和 parts 个分片文件 <name>.share-<n>,
+// 分片文件的大小与原文件无关。返回加密文件和分片文件的路径
+// 文件按 64 KiB 分块流式加密, 内存占用与文件大小无关
+func ShamirSplitFile(src, destDir string, parts, threshold int) (payload string, shareFiles []string, err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return "", nil, err
+	}
+	defer in.Close()
+
+	key := make([]byte, 32)
+	if err = randRead(key); err != nil {
+		return "", nil, err
+	}
+	shares, err := ShamirSplitShares(key, parts, threshold)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if err = os.MkdirAll(destDir, os.ModePerm); err != nil {
+		return "", nil, err
+	}
+
+	name := filepath.Base(src)
+	payload = filepath.Join(destDir, name+".enc")
+	header := append(bytes.Clone(shamirFileMagic), shares[0].SecretID[:]...)
+	if err = writeFileAtomic(payload, 0644, func(w io.Writer) error {
+		if _, err := w.Write(header); err != nil {
+			return err
+		}
+		return shamirFileEncrypt(w, in, key, header)
+	}); err != nil {
+		return "", nil, err
+	}
+
+	for _, share := range shares {
+		words, err := share.Words()
+		if err != nil {
+			return "", nil, err
+		}
+		content := fmt.Sprintf(
+			"# crab shamir share %d of %d, any %d shares restore %s\n# secret id: %x\n%s\n%s\n",
+			share.Index, share.Total, share.Threshold, name+".enc", share.SecretID,
+			share.String(), words,
+		)
+		shareFile := filepath.Join(destDir, fmt.Sprintf("%s.share-%d", name, share.Index))
+		if err = os.WriteFile(shareFile, []byte(content), 0600); err != nil {
+			return "", nil, err
+		}
+		shareFiles = append(shareFiles, shareFile)
+	}
+	return payload, shareFiles, nil
+}
+
+// ShamirCombineFile 用不少于门限个分片文件还原 ShamirSplitFile 加密的文件, 写入 dest
+// 所有分块校验通过后才会生成 dest, 文件被篡改、截断或分块被调换时返回 ErrAuthenticationFailed
+func ShamirCombineFile(payload, dest string, shareFiles ...string) error {
+	in, err := os.Open(payload)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	header := make([]byte, len(shamirFileMagic)+ShamirSecretIDLen)
+	if _, err := io.ReadFull(in, header); err != nil || !bytes.Equal(header[:len(shamirFileMagic)], shamirFileMagic) {
+		return fmt.Errorf("%w: %s is not a shamir encrypted file", ErrInvalidCiphertext, payload)
+	}
+	secretID := header[len(shamirFileMagic):]
+
+	shares := make([]*ShamirShare, 0, len(shareFiles))
+	for _, shareFile := range shareFiles {
+		share, err := ReadShamirShareFile(shareFile)
+		if err != nil {
+			return fmt.Errorf("%s: %w", shareFile, err)
+		}
+		if !bytes.Equal(share.SecretID[:], secretID) {
+			return fmt.Errorf("%w: %s does not belong to %s", ErrInvalidShares, shareFile, payload)
+		}
+		shares = append(shares, share)
+	}
+
+	key, err := ShamirCombineShares(shares)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(dest, 0644, func(w io.Writer) error {
+		return shamirFileDecrypt(w, in, key, header)
+	})
+}
+
+// shamirFileEncrypt 分块加密, 每块的 nonce 为 11 字节的块序号加 1 字节的结束标记,
+// 文件头作为附加数据, 可以发现分块被调换、截断或文件头被修改
+// 密钥每个文件随机生成, 所以 nonce 不需要随机部分
+func shamirFileEncrypt(w io.Writer, r io.Reader, key, header []byte) error {
+	aead, err := newShamirFileAEAD(key)
+	if err != nil {
+		return err
+	}
+	br := bufio.NewReaderSize(r, shamirFileChunkSize)
+	buf := make([]byte, shamirFileChunkSize, shamirFileChunkSize+aead.Overhead())
+	for counter := uint64(0); ; counter++ {
+		n, err := io.ReadFull(br, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		last := err != nil
+		if !last {
+			// 刚好读满一块时, 看下一个字节判断是否已到结尾
+			if _, err := br.Peek(1); err == io.EOF {
+				last = true
+			} else if err != nil {
+				return err
+			}
+		}
+		if _, err := w.Write(aead.Seal(buf[:0], shamirFileNonce(counter, last), buf[:n], header)); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+// shamirFileDecrypt 逐块解密 shamirFileEncrypt 的输出
+func shamirFileDecrypt(w io.Writer, r io.Reader, key, header []byte) error {
+	aead, err := newShamirFileAEAD(key)
+	if err != nil {
+		return err
+	}
+	br := bufio.NewReaderSize(r, shamirFileChunkSize+aead.Overhead())
+	buf := make([]byte, shamirFileChunkSize+aead.Overhead())
+	for counter := uint64(0); ; counter++ {
+		n, err := io.ReadFull(br, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		last := err != nil
+		if !last {
+			if _, err := br.Peek(1); err == io.EOF {
+				last = true
+			} else if err != nil {
+				return err
+			}
+		}
+		plaintext, err := aead.Open(buf[:0], shamirFileNonce(counter, last), buf[:n], header)
+		if err != nil {
+			return ErrAuthenticationFailed
+		}
+		if _, err := w.Write(plaintext); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+func newShamirFileAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := newAESCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func shamirFileNonce(counter uint64, last bool) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce[3:11], counter)
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+// writeFileAtomic 先写入同目录下的临时文件, write 成功后再重命名为 path, 失败时不会留下不完整的文件
+func writeFileAtomic(path string, perm os.FileMode, write func(w io.Writer) error) (err error) {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	bw := bufio.NewWriter(f)
+	if err = write(bw); err != nil {
+		return err
+	}
+	if err = bw.Flush(); err != nil {
+		return err
+	}
+	if err = f.Chmod(perm); err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// ReadShamirShareFile 读取分片文件, 忽略 # 开头的注释行
+// 文件中可以是十六进制、base64 或单词编码, 有多行时使用第一行能解析的内容
+func ReadShamirShareFile(path string) (*ShamirShare, error) {
+	lines, err := ReadFileByLine(path)
+	if err != nil {
+		return nil, err
+	}
+
+	err = fmt.Errorf("%w: no share found", ErrInvalidShares)
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var share *ShamirShare
+		if share, err = ParseShamirShare(line); err == nil {
+			return share, nil
+		}
+	}
+	return nil, err
+}
@@ -0,0 +1,117 @@
+package crab
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/serialt/crab/internal"
+)
+
+func TestShamirSplitFile(t *testing.T) {
+	assert := internal.NewAssert(t, "TestShamirSplitFile")
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "backup.bin")
+	content := []byte(strings.Repeat("crab shamir file escrow\n", 4096))
+	assert.IsNil(os.WriteFile(src, content, 0644))
+
+	payload, shareFiles, err := ShamirSplitFile(src, filepath.Join(dir, "escrow"), 5, 3)
+	assert.IsNil(err)
+	assert.Equal(filepath.Join(dir, "escrow", "backup.bin.enc"), payload)
+	assert.Equal(5, len(shareFiles))
+
+	info, err := os.Stat(shareFiles[0])
+	assert.IsNil(err)
+	assert.Greater(int64(1024), info.Size())
+
+	dest := filepath.Join(dir, "restored.bin")
+	assert.IsNil(ShamirCombineFile(payload, dest, shareFiles[4], shareFiles[1], shareFiles[2]))
+	restored, err := os.ReadFile(dest)
+	assert.IsNil(err)
+	assert.Equal(content, restored)
+
+	err = ShamirCombineFile(payload, dest, shareFiles[0], shareFiles[1])
+	assert.Equal(true, errors.Is(err, ErrInvalidShares))
+
+	// 其他文件的分片不能混用
+	_, otherShares, err := ShamirSplitFile(src, filepath.Join(dir, "other"), 5, 3)
+	assert.IsNil(err)
+	err = ShamirCombineFile(payload, dest, shareFiles[0], shareFiles[1], otherShares[2])
+	assert.Equal(true, errors.Is(err, ErrInvalidShares))
+}
+
+func TestShamirFileChunks(t *testing.T) {
+	assert := internal.NewAssert(t, "TestShamirFileChunks")
+
+	dir := t.TempDir()
+	key := []byte("0123456789abcdef0123456789abcdef")
+	header := append([]byte("CRABSSF1"), make([]byte, ShamirSecretIDLen)...)
+	for _, size := range []int{0, 1, shamirFileChunkSize, 2*shamirFileChunkSize + 1} {
+		content := make([]byte, size)
+		assert.IsNil(randRead(content))
+
+		var enc bytes.Buffer
+		assert.IsNil(shamirFileEncrypt(&enc, bytes.NewReader(content), key, header))
+		var dec bytes.Buffer
+		assert.IsNil(shamirFileDecrypt(&dec, bytes.NewReader(enc.Bytes()), key, header))
+		assert.Equal(true, bytes.Equal(content, dec.Bytes()))
+	}
+
+	content := make([]byte, 2*shamirFileChunkSize)
+	var enc bytes.Buffer
+	assert.IsNil(shamirFileEncrypt(&enc, bytes.NewReader(content), key, header))
+	chunk := shamirFileChunkSize + 16
+
+	// 截断最后一块
+	err := shamirFileDecrypt(io.Discard, bytes.NewReader(enc.Bytes()[:chunk]), key, header)
+	assert.Equal(ErrAuthenticationFailed, err)
+
+	// 调换分块
+	swapped := append(bytes.Clone(enc.Bytes()[chunk:]), enc.Bytes()[:chunk]...)
+	err = shamirFileDecrypt(io.Discard, bytes.NewReader(swapped), key, header)
+	assert.Equal(ErrAuthenticationFailed, err)
+
+	// 文件头作为附加数据
+	otherHeader := bytes.Clone(header)
+	otherHeader[len(otherHeader)-1] ^= 1
+	err = shamirFileDecrypt(io.Discard, bytes.NewReader(enc.Bytes()), key, otherHeader)
+	assert.Equal(ErrAuthenticationFailed, err)
+
+	// 篡改后不生成还原文件
+	src := filepath.Join(dir, "data.bin")
+	assert.IsNil(os.WriteFile(src, content, 0644))
+	payload, shareFiles, err := ShamirSplitFile(src, dir, 3, 2)
+	assert.IsNil(err)
+	data, err := os.ReadFile(payload)
+	assert.IsNil(err)
+	data[len(data)-1] ^= 1
+	assert.IsNil(os.WriteFile(payload, data, 0644))
+	dest := filepath.Join(dir, "restored.bin")
+	err = ShamirCombineFile(payload, dest, shareFiles[0], shareFiles[2])
+	assert.Equal(ErrAuthenticationFailed, err)
+	assert.Equal(false, IsExist(dest))
+}
+
+func TestReadShamirShareFile(t *testing.T) {
+	assert := internal.NewAssert(t, "TestReadShamirShareFile")
+
+	shares, err := ShamirSplitShares([]byte("test"), 3, 2)
+	assert.IsNil(err)
+	words, err := shares[0].Words()
+	assert.IsNil(err)
+
+	path := filepath.Join(t.TempDir(), "share.txt")
+	assert.IsNil(os.WriteFile(path, []byte("# written by hand\n\n"+words+"\n"), 0600))
+	share, err := ReadShamirShareFile(path)
+	assert.IsNil(err)
+	assert.Equal(shares[0], share)
+
+	assert.IsNil(os.WriteFile(path, []byte("# empty\n"), 0600))
+	_, err = ReadShamirShareFile(path)
+	assert.Equal(true, errors.Is(err, ErrInvalidShares))
+}
@@ -0,0 +1,163 @@
+package crab
+
+import "fmt"
+
+// 分片刷新和重新分发, 全程不在任何一处还原 secret。
+//
+// 刷新 (门限和参与者不变):
+//  1. 每个持有人调用 ShamirRefreshContribution, 把结果中 x 对应的更新发给该 x 的持有人
+//  2. 每个持有人收齐所有人的更新后调用 ShamirApplyRefresh 得到新分片, 并销毁旧分片
+//
+// 新旧分片不能混用, 刷新前泄露的旧分片因此失效。
+//
+// 重新分发 (修改门限或参与者):
+//  1. 至少门限个旧持有人各自调用 ShamirReshareContribution, 把结果中新 x 对应的子分片发给新持有人
+//  2. 每个新持有人用同一批旧持有人的子分片调用 ShamirReshareCombine 得到新分片
+//  3. 旧持有人销毁旧分片
+
+// ShamirRefreshContribution 生成一份刷新更新
+// xs 为所有持有人的 x 坐标 (分片的最后一个字节), length 为 secret 的字节数
+// 返回每个 x 对应的更新, 更新是常数项为 0 的随机多项式在 x 处的值
+func ShamirRefreshContribution(xs []uint8, length, threshold int) (map[uint8][]byte, error) {
+	if threshold < 2 || threshold > 255 {
+		return nil, fmt.Errorf("%w: threshold must be between 2 and 255", ErrInvalidThreshold)
+	}
+	if len(xs) < threshold {
+		return nil, fmt.Errorf("%w: fewer holders than threshold", ErrInvalidThreshold)
+	}
+	if length <= 0 {
+		return nil, fmt.Errorf("%w: secret length must be positive", ErrEmptyInput)
+	}
+	if err := checkXCoordinates(xs); err != nil {
+		return nil, err
+	}
+
+	updates := make(map[uint8][]byte, len(xs))
+	for _, x := range xs {
+		updates[x] = make([]byte, length)
+	}
+	for idx := 0; idx < length; idx++ {
+		p, err := makePolynomial(0, uint8(threshold-1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate polynomial: %w", err)
+		}
+		for _, x := range xs {
+			updates[x][idx] = p.evaluate(x)
+		}
+	}
+	return updates, nil
+}
+
+// ShamirApplyRefresh 把所有持有人 (包括自己) 发来的更新加到分片上, 返回新分片
+func ShamirApplyRefresh(share []byte, updates ...[]byte) ([]byte, error) {
+	if len(share) < 2 {
+		return nil, fmt.Errorf("%w: parts must be at least two bytes", ErrInvalidShares)
+	}
+	if len(updates) == 0 {
+		return nil, fmt.Errorf("%w: no refresh updates", ErrInvalidShares)
+	}
+
+	out := make([]byte, len(share))
+	copy(out, share)
+	for _, update := range updates {
+		if len(update) != len(share)-1 {
+			return nil, fmt.Errorf("%w: update length does not match share", ErrInvalidShares)
+		}
+		for idx, val := range update {
+			out[idx] = add(out[idx], val)
+		}
+	}
+	return out, nil
+}
+
+// ShamirReshareContribution 旧持有人把自己的分片再拆给新持有人
+// newXs 为新持有人的 x 坐标, newThreshold 为新的门限
+// 返回每个新 x 对应的子分片, 子分片格式与 ShamirSplit 相同, 最后一个字节为旧持有人的 x
+func ShamirReshareContribution(share []byte, newXs []uint8, newThreshold int) (map[uint8][]byte, error) {
+	if len(share) < 2 {
+		return nil, fmt.Errorf("%w: parts must be at least two bytes", ErrInvalidShares)
+	}
+	if newThreshold < 2 || newThreshold > 255 {
+		return nil, fmt.Errorf("%w: threshold must be between 2 and 255", ErrInvalidThreshold)
+	}
+	if len(newXs) < newThreshold {
+		return nil, fmt.Errorf("%w: fewer holders than threshold", ErrInvalidThreshold)
+	}
+	if err := checkXCoordinates(newXs); err != nil {
+		return nil, err
+	}
+
+	length := len(share) - 1
+	oldX := share[length]
+	subShares := make(map[uint8][]byte, len(newXs))
+	for _, x := range newXs {
+		subShares[x] = make([]byte, length+1)
+		subShares[x][length] = oldX
+	}
+	for idx := 0; idx < length; idx++ {
+		p, err := makePolynomial(share[idx], uint8(newThreshold-1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate polynomial: %w", err)
+		}
+		for _, x := range newXs {
+			subShares[x][idx] = p.evaluate(x)
+		}
+	}
+	return subShares, nil
+}
+
+// ShamirReshareCombine 新持有人用收到的子分片计算自己的新分片
+// 所有新持有人必须使用同一批旧持有人的子分片, 数量不少于旧门限
+func ShamirReshareCombine(newX uint8, subShares [][]byte) ([]byte, error) {
+	if newX == 0 {
+		return nil, fmt.Errorf("%w: x coordinate cannot be 0", ErrInvalidShares)
+	}
+	// 对旧持有人的子分片在 0 处插值, 得到新多项式在 newX 处的值
+	y, err := ShamirCombine(subShares)
+	if err != nil {
+		return nil, err
+	}
+	return append(y, newX), nil
+}
+
+// ShamirRandomXCoordinates 随机选择 n 个不重复且不在 exclude 中的 x 坐标
+// 用于给重新分发的新持有人分配 x 坐标
+func ShamirRandomXCoordinates(n int, exclude ...uint8) ([]uint8, error) {
+	used := map[uint8]bool{}
+	for _, x := range exclude {
+		used[x] = true
+	}
+	var candidates []uint8
+	for x := 1; x <= 255; x++ {
+		if !used[uint8(x)] {
+			candidates = append(candidates, uint8(x))
+		}
+	}
+	if n < 1 || n > len(candidates) {
+		return nil, fmt.Errorf("%w: cannot choose %d x coordinates", ErrInvalidThreshold, n)
+	}
+
+	perm, err := randPerm(len(candidates))
+	if err != nil {
+		return nil, err
+	}
+	xs := make([]uint8, n)
+	for i := range xs {
+		xs[i] = candidates[perm[i]]
+	}
+	return xs, nil
+}
+
+func checkXCoordinates(xs []uint8) error {
+	seen := map[uint8]bool{}
+	for _, x := range xs {
+		if x == 0 {
+			return fmt.Errorf("%w: x coordinate cannot be 0", ErrInvalidShares)
+		}
+		if seen[x] {
+			return fmt.Errorf("%w: duplicate x coordinate %d", ErrInvalidShares, x)
+		}
+		seen[x] = true
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package crab
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/serialt/crab/internal"
+)
+
+func TestShamirRefresh(t *testing.T) {
+	assert := internal.NewAssert(t, "TestShamirRefresh")
+
+	secret := []byte("root key material")
+	shares, err := ShamirSplit(secret, 5, 3)
+	assert.IsNil(err)
+
+	xs := make([]uint8, len(shares))
+	for i, share := range shares {
+		xs[i] = share[len(share)-1]
+	}
+
+	// 每个持有人生成一份更新, 发给所有持有人
+	received := map[uint8][][]byte{}
+	for range shares {
+		updates, err := ShamirRefreshContribution(xs, len(secret), 3)
+		assert.IsNil(err)
+		for x, update := range updates {
+			received[x] = append(received[x], update)
+		}
+	}
+
+	refreshed := make([][]byte, len(shares))
+	for i, share := range shares {
+		refreshed[i], err = ShamirApplyRefresh(share, received[xs[i]]...)
+		assert.IsNil(err)
+		assert.Equal(xs[i], refreshed[i][len(secret)])
+		assert.Equal(false, bytes.Equal(share, refreshed[i]))
+	}
+
+	got, err := ShamirCombine([][]byte{refreshed[0], refreshed[2], refreshed[4]})
+	assert.IsNil(err)
+	assert.Equal(secret, got)
+
+	got, err = ShamirCombine([][]byte{refreshed[1], refreshed[3], refreshed[4]})
+	assert.IsNil(err)
+	assert.Equal(secret, got)
+
+	// 旧分片与新分片混用无法还原
+	got, err = ShamirCombine([][]byte{shares[0], refreshed[2], refreshed[4]})
+	assert.IsNil(err)
+	assert.Equal(false, bytes.Equal(secret, got))
+
+	_, err = ShamirApplyRefresh(shares[0], []byte("x"))
+	assert.Equal(true, errors.Is(err, ErrInvalidShares))
+}
+
+func TestShamirReshare(t *testing.T) {
+	assert := internal.NewAssert(t, "TestShamirReshare")
+
+	secret := []byte("root key material")
+	shares, err := ShamirSplit(secret, 5, 3)
+	assert.IsNil(err)
+
+	oldXs := make([]uint8, len(shares))
+	for i, share := range shares {
+		oldXs[i] = share[len(share)-1]
+	}
+	newXs, err := ShamirRandomXCoordinates(7, oldXs...)
+	assert.IsNil(err)
+	assert.Equal(7, len(newXs))
+	assert.IsNil(checkXCoordinates(append(append([]uint8{}, oldXs...), newXs...)))
+
+	// 3-of-5 改为 4-of-7, 由旧持有人 0, 2, 4 参与
+	received := map[uint8][][]byte{}
+	for _, i := range []int{0, 2, 4} {
+		subShares, err := ShamirReshareContribution(shares[i], newXs, 4)
+		assert.IsNil(err)
+		for x, sub := range subShares {
+			received[x] = append(received[x], sub)
+		}
+	}
+
+	newShares := make([][]byte, len(newXs))
+	for i, x := range newXs {
+		newShares[i], err = ShamirReshareCombine(x, received[x])
+		assert.IsNil(err)
+	}
+
+	got, err := ShamirCombine([][]byte{newShares[6], newShares[1], newShares[3], newShares[0]})
+	assert.IsNil(err)
+	assert.Equal(secret, got)
+
+	got, err = ShamirCombine([][]byte{newShares[6], newShares[1], newShares[3]})
+	assert.IsNil(err)
+	assert.Equal(false, bytes.Equal(secret, got))
+
+	_, err = ShamirReshareContribution(shares[0], newXs[:2], 4)
+	assert.Equal(true, errors.Is(err, ErrInvalidThreshold))
+	_, err = ShamirRefreshContribution([]uint8{1, 2, 2}, 4, 2)
+	assert.Equal(true, errors.Is(err, ErrInvalidShares))
+}
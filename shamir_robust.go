@@ -0,0 +1,248 @@
+package crab
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ShamirCombineRobust 在分片多于门限时校验一致性, 并用 Berlekamp-Welch 纠错还原 secret
+// threshold 为拆分时的门限, 每个字节位置最多可以纠正 (len(parts)-threshold)/2 个损坏的分片,
+// 各字节位置独立纠错, 损坏的分片总数可以超过这个数量
+// faulty 为所有字节位置上损坏分片在 parts 中下标的并集; 某个位置损坏过多无法纠正时返回 ErrInvalidShares
+func ShamirCombineRobust(parts [][]byte, threshold int) (secret []byte, faulty []int, err error) {
+	if threshold < 2 || threshold > 255 {
+		return nil, nil, fmt.Errorf("%w: threshold must be between 2 and 255", ErrInvalidThreshold)
+	}
+	if len(parts) < threshold {
+		return nil, nil, fmt.Errorf("%w: need %d parts, got %d", ErrInvalidShares, threshold, len(parts))
+	}
+
+	// 长度和重复检查与 ShamirCombine 一致
+	firstPartLen := len(parts[0])
+	if firstPartLen < 2 {
+		return nil, nil, fmt.Errorf("%w: parts must be at least two bytes", ErrInvalidShares)
+	}
+	xSamples := make([]uint8, len(parts))
+	checkMap := map[byte]bool{}
+	for i, part := range parts {
+		if len(part) != firstPartLen {
+			return nil, nil, fmt.Errorf("%w: all parts must be the same length", ErrInvalidShares)
+		}
+		samp := part[firstPartLen-1]
+		if checkMap[samp] || samp == 0 {
+			return nil, nil, fmt.Errorf("%w: duplicate part detected", ErrInvalidShares)
+		}
+		checkMap[samp] = true
+		xSamples[i] = samp
+	}
+
+	maxErrors := (len(parts) - threshold) / 2
+	bad := map[int]bool{}
+	secret = make([]byte, firstPartLen-1)
+	ySamples := make([]uint8, len(parts))
+	for idx := range secret {
+		for i, part := range parts {
+			ySamples[i] = part[idx]
+		}
+
+		p, ok := consistentPolynomial(xSamples, ySamples, threshold)
+		if !ok {
+			if maxErrors == 0 {
+				return nil, nil, fmt.Errorf("%w: inconsistent parts detected, need at least %d parts to correct", ErrInvalidShares, threshold+2)
+			}
+			if p, ok = berlekampWelch(xSamples, ySamples, threshold, maxErrors); !ok {
+				return nil, nil, fmt.Errorf("%w: too many corrupted parts to correct", ErrInvalidShares)
+			}
+		}
+
+		for i := range parts {
+			if p.evaluate(xSamples[i]) != ySamples[i] {
+				bad[i] = true
+			}
+		}
+		secret[idx] = p.coefficients[0]
+	}
+
+	for i := range bad {
+		faulty = append(faulty, i)
+	}
+	sort.Ints(faulty)
+	return secret, faulty, nil
+}
+
+// consistentPolynomial 用前 k 个点插值出多项式, 并检查所有点都在多项式上
+func consistentPolynomial(xs, ys []uint8, k int) (polynomial, bool) {
+	p := polynomial{coefficients: interpolateCoefficients(xs[:k], ys[:k])}
+	for i := k; i < len(xs); i++ {
+		if p.evaluate(xs[i]) != ys[i] {
+			return p, false
+		}
+	}
+	return p, true
+}
+
+// berlekampWelch 在最多 e 个错误的情况下还原次数小于 k 的多项式
+// 求解 Q(x_i) = y_i * E(x_i), E 为 e 次首一错误定位多项式, Q 的次数不超过 e+k-1, 然后 P = Q / E
+func berlekampWelch(xs, ys []uint8, k, e int) (polynomial, bool) {
+	for ; e > 0; e-- {
+		n := len(xs)
+		qLen := e + k
+		cols := qLen + e
+		// 增广矩阵: [q_0..q_{e+k-1}, e_0..e_{e-1} | y_i * x_i^e]
+		matrix := make([][]uint8, n)
+		for i := range matrix {
+			row := make([]uint8, cols+1)
+			var power uint8 = 1
+			for j := 0; j < qLen; j++ {
+				row[j] = power
+				if j < e {
+					row[qLen+j] = mult(ys[i], power)
+				}
+				if j == e {
+					row[cols] = mult(ys[i], power)
+				}
+				power = mult(power, xs[i])
+			}
+			matrix[i] = row
+		}
+
+		solution, ok := gfSolve(matrix, cols)
+		if !ok {
+			continue
+		}
+		q := solution[:qLen]
+		errLocator := append(append([]uint8{}, solution[qLen:]...), 1)
+		coefficients, ok := gfPolyDiv(q, errLocator)
+		if !ok || len(coefficients) > k {
+			continue
+		}
+		p := polynomial{coefficients: coefficients}
+
+		agree := 0
+		for i := range xs {
+			if p.evaluate(xs[i]) == ys[i] {
+				agree++
+			}
+		}
+		if agree >= n-e {
+			return p, true
+		}
+	}
+	return polynomial{}, false
+}
+
+// interpolateCoefficients 拉格朗日插值求出经过所有点的多项式系数
+func interpolateCoefficients(xs, ys []uint8) []uint8 {
+	k := len(xs)
+	out := make([]uint8, k)
+	for i := 0; i < k; i++ {
+		// basis = Π (x - x_j) / (x_i - x_j)
+		basis := []uint8{1}
+		var denom uint8 = 1
+		for j := 0; j < k; j++ {
+			if i == j {
+				continue
+			}
+			next := make([]uint8, len(basis)+1)
+			for d, c := range basis {
+				next[d+1] = add(next[d+1], c)
+				next[d] = add(next[d], mult(c, xs[j]))
+			}
+			basis = next
+			denom = mult(denom, add(xs[i], xs[j]))
+		}
+		scale := div(ys[i], denom)
+		for d, c := range basis {
+			out[d] = add(out[d], mult(c, scale))
+		}
+	}
+	return out
+}
+
+// gfSolve 高斯消元求解 GF(2^8) 上的线性方程组, 自由变量取 0
+func gfSolve(matrix [][]uint8, cols int) ([]uint8, bool) {
+	pivots := make([]int, 0, cols)
+	row := 0
+	for col := 0; col < cols && row < len(matrix); col++ {
+		pivot := -1
+		for r := row; r < len(matrix); r++ {
+			if matrix[r][col] != 0 {
+				pivot = r
+				break
+			}
+		}
+		if pivot < 0 {
+			continue
+		}
+		matrix[row], matrix[pivot] = matrix[pivot], matrix[row]
+
+		inv := inverse(matrix[row][col])
+		for c := col; c <= cols; c++ {
+			matrix[row][c] = mult(matrix[row][c], inv)
+		}
+		for r := range matrix {
+			if r == row || matrix[r][col] == 0 {
+				continue
+			}
+			factor := matrix[r][col]
+			for c := col; c <= cols; c++ {
+				matrix[r][c] = add(matrix[r][c], mult(factor, matrix[row][c]))
+			}
+		}
+		pivots = append(pivots, col)
+		row++
+	}
+
+	// 全 0 行的常数项必须为 0, 否则无解
+	for r := row; r < len(matrix); r++ {
+		if matrix[r][cols] != 0 {
+			return nil, false
+		}
+	}
+
+	solution := make([]uint8, cols)
+	for r, col := range pivots {
+		solution[col] = matrix[r][cols]
+	}
+	return solution, true
+}
+
+// gfPolyDiv 多项式除法, 系数从低次到高次, 余数不为 0 时返回 false
+func gfPolyDiv(num, den []uint8) ([]uint8, bool) {
+	for len(den) > 0 && den[len(den)-1] == 0 {
+		den = den[:len(den)-1]
+	}
+	if len(den) == 0 {
+		return nil, false
+	}
+	rem := append([]uint8{}, num...)
+	if len(rem) < len(den) {
+		return []uint8{0}, isZeroPoly(rem)
+	}
+
+	quotient := make([]uint8, len(rem)-len(den)+1)
+	lead := inverse(den[len(den)-1])
+	for i := len(quotient) - 1; i >= 0; i-- {
+		coef := mult(rem[i+len(den)-1], lead)
+		quotient[i] = coef
+		for j, d := range den {
+			rem[i+j] = add(rem[i+j], mult(coef, d))
+		}
+	}
+	if !isZeroPoly(rem) {
+		return nil, false
+	}
+	for len(quotient) > 1 && quotient[len(quotient)-1] == 0 {
+		quotient = quotient[:len(quotient)-1]
+	}
+	return quotient, true
+}
+
+func isZeroPoly(p []uint8) bool {
+	for _, c := range p {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,99 @@
+package crab
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/serialt/crab/internal"
+)
+
+func TestShamirCombineRobust(t *testing.T) {
+	assert := internal.NewAssert(t, "TestShamirCombineRobust")
+
+	secret := []byte("robust secret sharing")
+	parts, err := ShamirSplit(secret, 9, 3)
+	assert.IsNil(err)
+
+	got, faulty, err := ShamirCombineRobust(parts, 3)
+	assert.IsNil(err)
+	assert.Equal(0, len(faulty))
+	assert.Equal(secret, got)
+
+	// 9 个分片、门限 3, 最多纠正 3 个损坏的分片
+	parts[1][0] ^= 0x55
+	parts[4][7] ^= 0x01
+	parts[4][8] ^= 0xff
+	for i := range secret {
+		parts[8][i] ^= byte(i + 1)
+	}
+	got, faulty, err = ShamirCombineRobust(parts, 3)
+	assert.IsNil(err)
+	assert.Equal([]int{1, 4, 8}, faulty)
+	assert.Equal(secret, got)
+
+	// 同一字节位置损坏超过纠错能力
+	parts[6][0] ^= 0x10
+	parts[7][0] ^= 0x20
+	_, _, err = ShamirCombineRobust(parts, 3)
+	assert.Equal(true, errors.Is(err, ErrInvalidShares))
+}
+
+func TestShamirCombineRobustPerByte(t *testing.T) {
+	assert := internal.NewAssert(t, "TestShamirCombineRobustPerByte")
+
+	secret := []byte("per byte")
+	parts, err := ShamirSplit(secret, 9, 3)
+	assert.IsNil(err)
+
+	// 每个位置最多纠正 3 个损坏的分片, 损坏的分片分布在不同位置时总数可以超过 3
+	for i := 0; i < 3; i++ {
+		parts[i][0] ^= byte(i + 1)
+		parts[i+3][5] ^= byte(i + 1)
+	}
+	got, faulty, err := ShamirCombineRobust(parts, 3)
+	assert.IsNil(err)
+	assert.Equal([]int{0, 1, 2, 3, 4, 5}, faulty)
+	assert.Equal(secret, got)
+
+	parts[6][0] ^= 0x80
+	_, _, err = ShamirCombineRobust(parts, 3)
+	assert.Equal(true, errors.Is(err, ErrInvalidShares))
+}
+
+func TestShamirCombineRobustDetectOnly(t *testing.T) {
+	assert := internal.NewAssert(t, "TestShamirCombineRobustDetectOnly")
+
+	secret := []byte("test")
+	parts, err := ShamirSplit(secret, 4, 3)
+	assert.IsNil(err)
+
+	got, faulty, err := ShamirCombineRobust(parts, 3)
+	assert.IsNil(err)
+	assert.Equal(0, len(faulty))
+	assert.Equal(secret, got)
+
+	// 只多一个分片时可以发现但无法纠正
+	parts[2][1] ^= 0x01
+	_, _, err = ShamirCombineRobust(parts, 3)
+	assert.Equal(true, errors.Is(err, ErrInvalidShares))
+
+	_, _, err = ShamirCombineRobust(parts[:2], 3)
+	assert.Equal(true, errors.Is(err, ErrInvalidShares))
+}
+
+func TestGFSolve(t *testing.T) {
+	assert := internal.NewAssert(t, "TestGFSolve")
+
+	p := polynomial{coefficients: []uint8{7, 3, 9}}
+	xs := []uint8{1, 2, 3}
+	ys := []uint8{p.evaluate(1), p.evaluate(2), p.evaluate(3)}
+	assert.Equal(p.coefficients, interpolateCoefficients(xs, ys))
+
+	// (x + 2)(x + 5) / (x + 5) = x + 2
+	prod := []uint8{mult(2, 5), add(2, 5), 1}
+	q, ok := gfPolyDiv(prod, []uint8{5, 1})
+	assert.Equal(true, ok)
+	assert.Equal([]uint8{2, 1}, q)
+	_, ok = gfPolyDiv(prod, []uint8{6, 1})
+	assert.Equal(false, ok)
+}
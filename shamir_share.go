@@ -0,0 +1,252 @@
+package crab
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+const (
+	// ShamirShareVersion 当前分片编码版本
+	ShamirShareVersion = 1
+
+	// ShamirSecretIDLen secret 标识的字节数
+	ShamirSecretIDLen = 8
+
+	// shamirShareHeaderLen version + threshold + total + index + x + secretID
+	shamirShareHeaderLen   = 5 + ShamirSecretIDLen
+	shamirShareChecksumLen = 4
+)
+
+// ShamirShare 自描述的 Shamir 分片
+// 二进制格式: version | threshold | total | index | x | secretID(8) | data | checksum(4)
+// checksum 为前面所有字节 SHA-256 的前 4 个字节, 截断或抄错的分片在解析时即可发现
+type ShamirShare struct {
+	Version   uint8
+	Threshold uint8
+	Total     uint8
+	// Index 分片序号, 从 1 开始, 便于人工核对 "第 3 份, 共 5 份"
+	Index uint8
+	// X 分片在多项式上的 x 坐标
+	X uint8
+	// SecretID 同一次拆分的所有分片相同, 用于拒绝混用不同 secret 的分片
+	SecretID [ShamirSecretIDLen]byte
+	// Data 每个字节对应的 y 值
+	Data []byte
+}
+
+// ShamirSplitShares 与 ShamirSplit 相同, 但返回自描述的分片
+func ShamirSplitShares(secret []byte, parts, threshold int) ([]*ShamirShare, error) {
+	raw, err := ShamirSplit(secret, parts, threshold)
+	if err != nil {
+		return nil, err
+	}
+
+	var secretID [ShamirSecretIDLen]byte
+	if err := randRead(secretID[:]); err != nil {
+		return nil, err
+	}
+
+	shares := make([]*ShamirShare, len(raw))
+	for i, part := range raw {
+		shares[i] = &ShamirShare{
+			Version:   ShamirShareVersion,
+			Threshold: uint8(threshold),
+			Total:     uint8(parts),
+			Index:     uint8(i + 1),
+			X:         part[len(part)-1],
+			SecretID:  secretID,
+			Data:      part[:len(part)-1],
+		}
+	}
+	return shares, nil
+}
+
+// ShamirCombineShares 校验分片属于同一个 secret 且数量达到门限后还原 secret
+func ShamirCombineShares(shares []*ShamirShare) ([]byte, error) {
+	if len(shares) == 0 {
+		return nil, fmt.Errorf("%w: no shares", ErrInvalidShares)
+	}
+	for i, s := range shares {
+		if s == nil {
+			return nil, fmt.Errorf("%w: share %d is nil", ErrInvalidShares, i)
+		}
+	}
+
+	first := shares[0]
+	if len(shares) < int(first.Threshold) {
+		return nil, fmt.Errorf("%w: need %d shares, got %d", ErrInvalidShares, first.Threshold, len(shares))
+	}
+
+	seen := map[uint8]bool{}
+	raw := make([][]byte, len(shares))
+	for i, s := range shares {
+		if s.Version != first.Version || s.Threshold != first.Threshold || s.Total != first.Total {
+			return nil, fmt.Errorf("%w: share %d has a different version or threshold", ErrInvalidShares, s.Index)
+		}
+		if s.SecretID != first.SecretID {
+			return nil, fmt.Errorf("%w: share %d belongs to a different secret", ErrInvalidShares, s.Index)
+		}
+		if seen[s.Index] {
+			return nil, fmt.Errorf("%w: duplicate share %d", ErrInvalidShares, s.Index)
+		}
+		seen[s.Index] = true
+		raw[i] = s.Raw()
+	}
+	return ShamirCombine(raw)
+}
+
+// Raw 返回 ShamirSplit 格式的分片, 即 data 后追加 x 坐标
+func (s *ShamirShare) Raw() []byte {
+	raw := make([]byte, len(s.Data)+1)
+	copy(raw, s.Data)
+	raw[len(s.Data)] = s.X
+	return raw
+}
+
+// MarshalBinary 编码为带校验和的二进制格式
+func (s *ShamirShare) MarshalBinary() ([]byte, error) {
+	if len(s.Data) == 0 {
+		return nil, fmt.Errorf("%w: share has no data", ErrInvalidShares)
+	}
+	buf := make([]byte, 0, shamirShareHeaderLen+len(s.Data)+shamirShareChecksumLen)
+	buf = append(buf, s.Version, s.Threshold, s.Total, s.Index, s.X)
+	buf = append(buf, s.SecretID[:]...)
+	buf = append(buf, s.Data...)
+	sum := sha256.Sum256(buf)
+	return append(buf, sum[:shamirShareChecksumLen]...), nil
+}
+
+// UnmarshalBinary 解析二进制格式并校验 checksum
+func (s *ShamirShare) UnmarshalBinary(data []byte) error {
+	if len(data) < shamirShareHeaderLen+1+shamirShareChecksumLen {
+		return fmt.Errorf("%w: share too short", ErrInvalidShares)
+	}
+	body, checksum := data[:len(data)-shamirShareChecksumLen], data[len(data)-shamirShareChecksumLen:]
+	sum := sha256.Sum256(body)
+	if !bytes.Equal(sum[:shamirShareChecksumLen], checksum) {
+		return fmt.Errorf("%w: share checksum mismatch", ErrInvalidShares)
+	}
+	if body[0] != ShamirShareVersion {
+		return fmt.Errorf("%w: share version %d", ErrUnsupportedAlgorithm, body[0])
+	}
+	if body[1] < 2 || body[2] < body[1] || body[3] == 0 || body[3] > body[2] || body[4] == 0 {
+		return fmt.Errorf("%w: share header is inconsistent", ErrInvalidShares)
+	}
+
+	s.Version, s.Threshold, s.Total, s.Index, s.X = body[0], body[1], body[2], body[3], body[4]
+	copy(s.SecretID[:], body[5:shamirShareHeaderLen])
+	s.Data = append([]byte(nil), body[shamirShareHeaderLen:]...)
+	return nil
+}
+
+// Hex 编码为十六进制字符串
+func (s *ShamirShare) Hex() (string, error) {
+	data, err := s.MarshalBinary()
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(data), nil
+}
+
+// Base64 编码为 URL 安全、无填充的 base64 字符串
+func (s *ShamirShare) Base64() (string, error) {
+	data, err := s.MarshalBinary()
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// Words 编码为单词序列, 每个字节对应一个单词, 便于抄写或口述
+func (s *ShamirShare) Words() (string, error) {
+	data, err := s.MarshalBinary()
+	if err != nil {
+		return "", err
+	}
+	words := make([]string, len(data))
+	for i, b := range data {
+		words[i] = shamirWord(b)
+	}
+	return strings.Join(words, " "), nil
+}
+
+// String 返回十六进制编码, 编码失败时返回空字符串
+func (s *ShamirShare) String() string {
+	str, _ := s.Hex()
+	return str
+}
+
+// ParseShamirShareHex 解析十六进制编码的分片
+func ParseShamirShareHex(str string) (*ShamirShare, error) {
+	data, err := hex.DecodeString(strings.TrimSpace(str))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidShares, err)
+	}
+	return parseShamirShare(data)
+}
+
+// ParseShamirShareBase64 解析 base64 编码的分片, 支持标准和 URL 安全字符集
+func ParseShamirShareBase64(str string) (*ShamirShare, error) {
+	str = strings.TrimRight(strings.TrimSpace(str), "=")
+	str = strings.NewReplacer("+", "-", "/", "_").Replace(str)
+	data, err := base64.RawURLEncoding.DecodeString(str)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidShares, err)
+	}
+	return parseShamirShare(data)
+}
+
+// ParseShamirShareWords 解析单词序列编码的分片
+// 单词不区分大小写, 也可以只写前 4 个字母; 无法识别的单词会报告位置
+func ParseShamirShareWords(str string) (*ShamirShare, error) {
+	words := strings.Fields(str)
+	data := make([]byte, len(words))
+	for i, w := range words {
+		b, ok := shamirWordIndex(w)
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown word %q at position %d", ErrInvalidShares, w, i+1)
+		}
+		data[i] = b
+	}
+	return parseShamirShare(data)
+}
+
+// ParseShamirShare 自动识别单词、十六进制或 base64 编码的分片
+func ParseShamirShare(str string) (*ShamirShare, error) {
+	str = strings.TrimSpace(str)
+	if strings.ContainsAny(str, " \t\n") {
+		return ParseShamirShareWords(str)
+	}
+	if s, err := ParseShamirShareHex(str); err == nil {
+		return s, nil
+	}
+	return ParseShamirShareBase64(str)
+}
+
+func parseShamirShare(data []byte) (*ShamirShare, error) {
+	s := &ShamirShare{}
+	if err := s.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// shamirWord 从 BIP39 英文词表中每隔 8 个取一个, 组成 256 个单词的词表
+func shamirWord(b byte) string {
+	return bip39English[int(b)*8]
+}
+
+func shamirWordIndex(word string) (byte, bool) {
+	word = strings.ToLower(word)
+	for i := 0; i < 256; i++ {
+		w := shamirWord(byte(i))
+		if w == word || (len(word) == 4 && strings.HasPrefix(w, word)) {
+			return byte(i), true
+		}
+	}
+	return 0, false
+}
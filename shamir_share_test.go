@@ -0,0 +1,111 @@
+package crab
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/serialt/crab/internal"
+)
+
+func TestShamirShares(t *testing.T) {
+	assert := internal.NewAssert(t, "TestShamirShares")
+
+	secret := []byte("correct horse battery staple")
+	shares, err := ShamirSplitShares(secret, 5, 3)
+	assert.IsNil(err)
+	assert.Equal(5, len(shares))
+
+	for i, s := range shares {
+		assert.Equal(uint8(i+1), s.Index)
+		assert.Equal(uint8(3), s.Threshold)
+		assert.Equal(uint8(5), s.Total)
+		assert.Equal(shares[0].SecretID, s.SecretID)
+	}
+
+	got, err := ShamirCombineShares([]*ShamirShare{shares[4], shares[0], shares[2]})
+	assert.IsNil(err)
+	assert.Equal(secret, got)
+
+	_, err = ShamirCombineShares(shares[:2])
+	assert.Equal(true, errors.Is(err, ErrInvalidShares))
+
+	_, err = ShamirCombineShares([]*ShamirShare{shares[0], shares[1], shares[1]})
+	assert.Equal(true, errors.Is(err, ErrInvalidShares))
+
+	_, err = ShamirCombineShares([]*ShamirShare{nil, shares[1], shares[2]})
+	assert.Equal(true, errors.Is(err, ErrInvalidShares))
+	_, err = ShamirCombineShares([]*ShamirShare{shares[0], shares[1], nil})
+	assert.Equal(true, errors.Is(err, ErrInvalidShares))
+
+	// 不同 secret 的分片不能混用
+	others, err := ShamirSplitShares(secret, 5, 3)
+	assert.IsNil(err)
+	_, err = ShamirCombineShares([]*ShamirShare{shares[0], shares[1], others[2]})
+	assert.Equal(true, errors.Is(err, ErrInvalidShares))
+}
+
+func TestShamirShareEncoding(t *testing.T) {
+	assert := internal.NewAssert(t, "TestShamirShareEncoding")
+
+	shares, err := ShamirSplitShares([]byte("test"), 3, 2)
+	assert.IsNil(err)
+	share := shares[1]
+
+	hexStr, err := share.Hex()
+	assert.IsNil(err)
+	b64Str, err := share.Base64()
+	assert.IsNil(err)
+	words, err := share.Words()
+	assert.IsNil(err)
+	assert.Equal(len(share.Data)+17, len(strings.Fields(words)))
+
+	for _, str := range []string{hexStr, b64Str, words, strings.ToUpper(words)} {
+		parsed, err := ParseShamirShare(str)
+		assert.IsNil(err)
+		assert.Equal(share, parsed)
+	}
+
+	// 只写前 4 个字母
+	fields := strings.Fields(words)
+	for i, w := range fields {
+		if len(w) > 4 {
+			fields[i] = w[:4]
+		}
+	}
+	parsed, err := ParseShamirShareWords(strings.Join(fields, " "))
+	assert.IsNil(err)
+	assert.Equal(share, parsed)
+
+	// 截断
+	_, err = ParseShamirShareHex(hexStr[:len(hexStr)-2])
+	assert.Equal(true, errors.Is(err, ErrInvalidShares))
+
+	// 抄错一位
+	data, err := share.MarshalBinary()
+	assert.IsNil(err)
+	data[len(data)-6] ^= 0x10
+	err = (&ShamirShare{}).UnmarshalBinary(data)
+	assert.Equal(true, errors.Is(err, ErrInvalidShares))
+
+	// 拼写错误的单词
+	fields = strings.Fields(words)
+	fields[3] = "notaword"
+	_, err = ParseShamirShareWords(strings.Join(fields, " "))
+	assert.Equal(true, errors.Is(err, ErrInvalidShares))
+	assert.Equal(true, strings.Contains(err.Error(), "position 4"))
+}
+
+func TestShamirWordList(t *testing.T) {
+	assert := internal.NewAssert(t, "TestShamirWordList")
+
+	seen := map[string]bool{}
+	for i := 0; i < 256; i++ {
+		w := shamirWord(byte(i))
+		b, ok := shamirWordIndex(w)
+		assert.Equal(true, ok)
+		assert.Equal(byte(i), b)
+		seen[w] = true
+	}
+	assert.Equal(256, len(seen))
+}
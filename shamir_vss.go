@@ -0,0 +1,259 @@
+package crab
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+)
+
+// Feldman VSS 使用 RFC 3526 的 2048 位 MODP 群, p 为安全素数 p = 2q + 1,
+// g = 2 生成阶为 q 的子群。GF(256) 上无法做承诺, 所以可验证分片在 Z_q 上计算。
+var (
+	vssP, _ = new(big.Int).SetString(
+		"FFFFFFFFFFFFFFFFC90FDAA22168C234C4C6628B80DC1CD1"+
+			"29024E088A67CC74020BBEA63B139B22514A08798E3404DD"+
+			"EF9519B3CD3A431B302B0A6DF25F14374FE1356D6D51C245"+
+			"E485B576625E7EC6F44C42E9A637ED6B0BFF5CB6F406B7ED"+
+			"EE386BFB5A899FA5AE9F24117C4B1FE649286651ECE45B3D"+
+			"C2007CB8A163BF0598DA48361C55D39A69163FA8FD24CF5F"+
+			"83655D23DCA3AD961C62F356208552BB9ED529077096966D"+
+			"670C354E4ABC9804F1746C08CA18217C32905E462E36CE3B"+
+			"E39E772C180E86039B2783A2EC07A28FB5C55DF06F4C52C9"+
+			"DE2BCBF6955817183995497CEA956AE515D2261898FA0510"+
+			"15728E5A8AACAA68FFFFFFFFFFFFFFFF", 16)
+	vssQ = new(big.Int).Rsh(vssP, 1)
+	vssG = big.NewInt(2)
+)
+
+// vssChunkSize 每个分块的字节数, 保证分块的值小于 q
+const vssChunkSize = 255
+
+// VSSShare 可验证的分片
+type VSSShare struct {
+	// Index 分片的 x 坐标, 从 1 开始
+	Index int `json:"index"`
+	// Values 每个分块在 x 处的值
+	Values []*big.Int `json:"values"`
+}
+
+// VSSCommitments 拆分时公布的承诺, 与分片一起分发, 不需要保密
+// 注意承诺中包含 g^secret, secret 必须是随机密钥这类高熵数据
+type VSSCommitments struct {
+	Threshold int `json:"threshold"`
+	// SecretLen secret 的字节数
+	SecretLen int `json:"secret_len"`
+	// Values 每个分块多项式系数的承诺 g^a mod p
+	Values [][]*big.Int `json:"values"`
+}
+
+// VSSBadSharesError 验证失败的分片
+type VSSBadSharesError struct {
+	Indices []int
+}
+
+func (e *VSSBadSharesError) Error() string {
+	idx := make([]string, len(e.Indices))
+	for i, v := range e.Indices {
+		idx[i] = fmt.Sprint(v)
+	}
+	return fmt.Sprintf("%v: bad shares %s", ErrInvalidShares, strings.Join(idx, ","))
+}
+
+func (e *VSSBadSharesError) Unwrap() error {
+	return ErrInvalidShares
+}
+
+// ShamirSplitVerifiable 使用 Feldman VSS 拆分 secret
+// 返回 parts 个分片和公开的承诺, 分片持有人可以用 ShamirVerifyShare 独立验证自己的分片
+func ShamirSplitVerifiable(secret []byte, parts, threshold int) ([]*VSSShare, *VSSCommitments, error) {
+	if parts < threshold {
+		return nil, nil, fmt.Errorf("%w: parts cannot be less than threshold", ErrInvalidThreshold)
+	}
+	if parts > 255 {
+		return nil, nil, fmt.Errorf("%w: parts cannot exceed 255", ErrInvalidThreshold)
+	}
+	if threshold < 2 {
+		return nil, nil, fmt.Errorf("%w: threshold must be at least 2", ErrInvalidThreshold)
+	}
+	if len(secret) == 0 {
+		return nil, nil, fmt.Errorf("%w: cannot split an empty secret", ErrEmptyInput)
+	}
+
+	chunks := vssChunks(secret)
+	commitments := &VSSCommitments{
+		Threshold: threshold,
+		SecretLen: len(secret),
+		Values:    make([][]*big.Int, len(chunks)),
+	}
+	shares := make([]*VSSShare, parts)
+	for i := range shares {
+		shares[i] = &VSSShare{Index: i + 1, Values: make([]*big.Int, len(chunks))}
+	}
+
+	for c, chunk := range chunks {
+		coefficients := make([]*big.Int, threshold)
+		coefficients[0] = new(big.Int).SetBytes(chunk)
+		for j := 1; j < threshold; j++ {
+			a, err := rand.Int(RandReader(), vssQ)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to generate polynomial: %w", err)
+			}
+			coefficients[j] = a
+		}
+
+		commitments.Values[c] = make([]*big.Int, threshold)
+		for j, a := range coefficients {
+			commitments.Values[c][j] = new(big.Int).Exp(vssG, a, vssP)
+		}
+
+		for _, share := range shares {
+			share.Values[c] = vssEvaluate(coefficients, share.Index)
+		}
+	}
+	return shares, commitments, nil
+}
+
+// ShamirVerifyShare 用公开的承诺验证分片, 不需要其他分片
+func ShamirVerifyShare(share *VSSShare, commitments *VSSCommitments) error {
+	if err := commitments.validate(); err != nil {
+		return err
+	}
+	if share == nil || share.Index < 1 || share.Index > 255 || len(share.Values) != len(commitments.Values) {
+		return &VSSBadSharesError{Indices: []int{vssShareIndex(share)}}
+	}
+
+	x := big.NewInt(int64(share.Index))
+	for c, y := range share.Values {
+		if y == nil || y.Sign() < 0 || y.Cmp(vssQ) >= 0 {
+			return &VSSBadSharesError{Indices: []int{share.Index}}
+		}
+
+		// g^y == Π C_j^(x^j)
+		left := new(big.Int).Exp(vssG, y, vssP)
+		right := big.NewInt(1)
+		power := big.NewInt(1)
+		for _, commitment := range commitments.Values[c] {
+			term := new(big.Int).Exp(commitment, power, vssP)
+			right.Mul(right, term).Mod(right, vssP)
+			power.Mul(power, x).Mod(power, vssQ)
+		}
+		if left.Cmp(right) != 0 {
+			return &VSSBadSharesError{Indices: []int{share.Index}}
+		}
+	}
+	return nil
+}
+
+// ShamirCombineVerifiable 验证每个分片后用合法分片还原 secret
+// bad 为验证失败的分片 x 坐标; 合法分片不足门限时返回 *VSSBadSharesError
+func ShamirCombineVerifiable(shares []*VSSShare, commitments *VSSCommitments) (secret []byte, bad []int, err error) {
+	if err = commitments.validate(); err != nil {
+		return nil, nil, err
+	}
+
+	seen := map[int]bool{}
+	var good []*VSSShare
+	for _, share := range shares {
+		if share != nil && seen[share.Index] {
+			return nil, nil, fmt.Errorf("%w: duplicate share %d", ErrInvalidShares, share.Index)
+		}
+		if ShamirVerifyShare(share, commitments) != nil {
+			bad = append(bad, vssShareIndex(share))
+			continue
+		}
+		seen[share.Index] = true
+		good = append(good, share)
+	}
+	sort.Ints(bad)
+
+	if len(good) < commitments.Threshold {
+		if len(bad) > 0 {
+			return nil, bad, &VSSBadSharesError{Indices: bad}
+		}
+		return nil, nil, fmt.Errorf("%w: need %d shares, got %d", ErrInvalidShares, commitments.Threshold, len(good))
+	}
+	good = good[:commitments.Threshold]
+
+	secret = make([]byte, 0, commitments.SecretLen)
+	for c := range commitments.Values {
+		value := vssInterpolate(good, c)
+		size := vssChunkLen(commitments.SecretLen, c)
+		if value.BitLen() > size*8 {
+			return nil, bad, fmt.Errorf("%w: reconstructed chunk %d is out of range", ErrInvalidShares, c)
+		}
+		secret = append(secret, value.FillBytes(make([]byte, size))...)
+	}
+	return secret, bad, nil
+}
+
+func (c *VSSCommitments) validate() error {
+	if c == nil || c.Threshold < 2 || c.SecretLen <= 0 || len(c.Values) != (c.SecretLen+vssChunkSize-1)/vssChunkSize {
+		return fmt.Errorf("%w: malformed commitments", ErrInvalidShares)
+	}
+	for _, values := range c.Values {
+		if len(values) != c.Threshold {
+			return fmt.Errorf("%w: malformed commitments", ErrInvalidShares)
+		}
+		for _, v := range values {
+			if v == nil || v.Sign() <= 0 || v.Cmp(vssP) >= 0 {
+				return fmt.Errorf("%w: malformed commitments", ErrInvalidShares)
+			}
+		}
+	}
+	return nil
+}
+
+// vssEvaluate 用 Horner 方法计算 f(x) mod q
+func vssEvaluate(coefficients []*big.Int, x int) *big.Int {
+	bx := big.NewInt(int64(x))
+	out := new(big.Int).Set(coefficients[len(coefficients)-1])
+	for i := len(coefficients) - 2; i >= 0; i-- {
+		out.Mul(out, bx).Add(out, coefficients[i]).Mod(out, vssQ)
+	}
+	return out
+}
+
+// vssInterpolate 拉格朗日插值计算第 c 个分块在 0 处的值
+func vssInterpolate(shares []*VSSShare, c int) *big.Int {
+	result := new(big.Int)
+	for i, si := range shares {
+		num, denom := big.NewInt(1), big.NewInt(1)
+		for j, sj := range shares {
+			if i == j {
+				continue
+			}
+			// basis *= (0 - xj) / (xi - xj)
+			num.Mul(num, big.NewInt(int64(-sj.Index))).Mod(num, vssQ)
+			denom.Mul(denom, big.NewInt(int64(si.Index-sj.Index))).Mod(denom, vssQ)
+		}
+		basis := num.Mul(num, denom.ModInverse(denom, vssQ))
+		term := basis.Mul(basis, si.Values[c])
+		result.Add(result, term).Mod(result, vssQ)
+	}
+	return result
+}
+
+func vssChunks(secret []byte) [][]byte {
+	var chunks [][]byte
+	for len(secret) > vssChunkSize {
+		chunks = append(chunks, secret[:vssChunkSize])
+		secret = secret[vssChunkSize:]
+	}
+	return append(chunks, secret)
+}
+
+func vssChunkLen(secretLen, c int) int {
+	if rest := secretLen - c*vssChunkSize; rest < vssChunkSize {
+		return rest
+	}
+	return vssChunkSize
+}
+
+func vssShareIndex(share *VSSShare) int {
+	if share == nil {
+		return 0
+	}
+	return share.Index
+}
@@ -0,0 +1,78 @@
+package crab
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/serialt/crab/internal"
+)
+
+func TestShamirVerifiable(t *testing.T) {
+	assert := internal.NewAssert(t, "TestShamirVerifiable")
+
+	secret := GenerateKey(32)
+	shares, commitments, err := ShamirSplitVerifiable(secret, 5, 3)
+	assert.IsNil(err)
+	assert.Equal(5, len(shares))
+
+	for _, share := range shares {
+		assert.IsNil(ShamirVerifyShare(share, commitments))
+	}
+
+	got, bad, err := ShamirCombineVerifiable([]*VSSShare{shares[3], shares[1], shares[4]}, commitments)
+	assert.IsNil(err)
+	assert.Equal(0, len(bad))
+	assert.Equal(secret, got)
+
+	// 承诺和分片可以序列化后分发
+	data, err := json.Marshal(commitments)
+	assert.IsNil(err)
+	var decoded VSSCommitments
+	assert.IsNil(json.Unmarshal(data, &decoded))
+	assert.IsNil(ShamirVerifyShare(shares[0], &decoded))
+}
+
+func TestShamirVerifiableBadShares(t *testing.T) {
+	assert := internal.NewAssert(t, "TestShamirVerifiableBadShares")
+
+	// 超过一个分块且以 0 开头
+	secret := append([]byte{0, 0}, GenerateKey(300)...)
+	shares, commitments, err := ShamirSplitVerifiable(secret, 5, 3)
+	assert.IsNil(err)
+	assert.Equal(2, len(commitments.Values))
+
+	shares[1].Values[1] = new(big.Int).Add(shares[1].Values[1], big.NewInt(1))
+	err = ShamirVerifyShare(shares[1], commitments)
+	var badErr *VSSBadSharesError
+	assert.Equal(true, errors.As(err, &badErr))
+	assert.Equal([]int{2}, badErr.Indices)
+	assert.Equal(true, errors.Is(err, ErrInvalidShares))
+
+	got, bad, err := ShamirCombineVerifiable(shares, commitments)
+	assert.IsNil(err)
+	assert.Equal([]int{2}, bad)
+	assert.Equal(secret, got)
+
+	shares[3].Values[0] = big.NewInt(42)
+	_, bad, err = ShamirCombineVerifiable(shares[1:4], commitments)
+	assert.Equal(true, errors.As(err, &badErr))
+	assert.Equal([]int{2, 4}, bad)
+}
+
+func TestShamirVerifiableInvalid(t *testing.T) {
+	assert := internal.NewAssert(t, "TestShamirVerifiableInvalid")
+
+	_, _, err := ShamirSplitVerifiable([]byte("test"), 2, 3)
+	assert.Equal(true, errors.Is(err, ErrInvalidThreshold))
+	_, _, err = ShamirSplitVerifiable(nil, 3, 2)
+	assert.Equal(true, errors.Is(err, ErrEmptyInput))
+
+	shares, commitments, err := ShamirSplitVerifiable([]byte("test"), 3, 2)
+	assert.IsNil(err)
+	_, _, err = ShamirCombineVerifiable([]*VSSShare{shares[0], shares[0]}, commitments)
+	assert.Equal(true, errors.Is(err, ErrInvalidShares))
+	_, _, err = ShamirCombineVerifiable(shares, &VSSCommitments{})
+	assert.Equal(true, errors.Is(err, ErrInvalidShares))
+}
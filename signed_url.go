@@ -0,0 +1,134 @@
+package crab
+
+import (
+	"crypto/hmac"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// 签名链接使用的 query 参数
+const (
+	// SignedURLExpires 过期时间, unix 秒
+	SignedURLExpires = "expires"
+	// SignedURLSignature 签名, HMAC-SHA256 的 hex
+	SignedURLSignature = "signature"
+	// SignedURLIP 绑定的客户端 IP, 放在 extraParams 中时请求的客户端 IP 必须一致, 比较前两边都转为规范形式
+	// 默认使用 RemoteAddr; 在反向代理后面时用 SignedURLOpt.ClientIP 读取代理设置的头,
+	// 代理必须覆盖客户端发来的 X-Real-IP 和 X-Forwarded-For, 否则绑定可以被伪造
+	SignedURLIP = "ip"
+)
+
+// SignedURLOpt 验证签名链接的配置
+type SignedURLOpt struct {
+	// ClientIP 返回请求的客户端 IP, 用于校验 SignedURLIP, 默认使用 RemoteAddr
+	// 只有在可信代理覆盖了转发头时才能使用 IPGet
+	ClientIP func(r *http.Request) string
+}
+
+// SignURL 为链接签名, 返回带过期时间和签名的链接
+// extraParams 会加入 query 并参与签名, 例如 {"ip": {clientIP}} 把链接绑定到客户端 IP
+// 签名内容为 path 和按 key 排序的 query, 不包含 host, 经过反向代理改写 host 后仍然有效
+func SignURL(rawURL, secret string, expiry time.Duration, extraParams url.Values) (string, error) {
+	if secret == "" {
+		return "", fmt.Errorf("%w: url secret", ErrEmptyInput)
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	query := u.Query()
+	for key, values := range extraParams {
+		query[key] = values
+	}
+	query.Del(SignedURLSignature)
+	if ip := query.Get(SignedURLIP); ip != "" {
+		query.Set(SignedURLIP, canonicalIP(ip))
+	}
+	query.Set(SignedURLExpires, strconv.FormatInt(time.Now().Add(expiry).Unix(), 10))
+	query.Set(SignedURLSignature, signedURLSignature(secret, u.EscapedPath(), query))
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// VerifySignedURL 验证请求的链接签名, 过期返回 ErrSignatureExpired, 被篡改或 IP 不一致返回 ErrInvalidSignature
+// 绑定的 IP 与 RemoteAddr 比较
+func VerifySignedURL(r *http.Request, secret string) error {
+	return VerifySignedURLWithOpt(r, secret, SignedURLOpt{})
+}
+
+// VerifySignedURLWithOpt 同 VerifySignedURL, 可以指定获取客户端 IP 的方式
+func VerifySignedURLWithOpt(r *http.Request, secret string, opt SignedURLOpt) error {
+	if opt.ClientIP == nil {
+		opt.ClientIP = remoteIP
+	}
+	query := r.URL.Query()
+	sig, err := hex.DecodeString(query.Get(SignedURLSignature))
+	if err != nil || len(sig) == 0 {
+		return fmt.Errorf("%w: missing url signature", ErrInvalidSignature)
+	}
+	query.Del(SignedURLSignature)
+
+	expected, _ := hex.DecodeString(signedURLSignature(secret, r.URL.EscapedPath(), query))
+	if !hmac.Equal(sig, expected) {
+		return ErrInvalidSignature
+	}
+
+	expires, err := strconv.ParseInt(query.Get(SignedURLExpires), 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: malformed expires", ErrInvalidSignature)
+	}
+	if time.Now().Unix() > expires {
+		return fmt.Errorf("%w: url expired at %s", ErrSignatureExpired, time.Unix(expires, 0).Format(time.RFC3339))
+	}
+	if ip := query.Get(SignedURLIP); ip != "" && canonicalIP(ip) != canonicalIP(opt.ClientIP(r)) {
+		return fmt.Errorf("%w: url is bound to another ip", ErrInvalidSignature)
+	}
+	return nil
+}
+
+// SignedURLHandler 只放行签名有效的请求, 其他请求返回 403
+func SignedURLHandler(secret string, next http.Handler) http.Handler {
+	return SignedURLHandlerWithOpt(secret, SignedURLOpt{}, next)
+}
+
+// SignedURLHandlerWithOpt 同 SignedURLHandler, 可以指定获取客户端 IP 的方式
+func SignedURLHandlerWithOpt(secret string, opt SignedURLOpt, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := VerifySignedURLWithOpt(r, secret, opt); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// remoteIP 返回 RemoteAddr 中的 IP, 不读取客户端可以伪造的转发头
+func remoteIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	return ip
+}
+
+// canonicalIP 把 IP 转为规范形式, IPv4 映射的 IPv6 地址转为 IPv4, 无法解析时原样返回
+// ::1 和 127.0.0.1 是不同的地址, 签名时需要绑定客户端实际使用的地址族
+func canonicalIP(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ip
+	}
+	return addr.Unmap().String()
+}
+
+// signedURLSignature url.Values.Encode 按 key 排序, 作为规范化的 query
+func signedURLSignature(secret, path string, query url.Values) string {
+	return HmacSha256(path+"?"+query.Encode(), secret)
+}
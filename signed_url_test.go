@@ -0,0 +1,120 @@
+package crab
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/serialt/crab/internal"
+)
+
+func TestSignURL(t *testing.T) {
+	assert := internal.NewAssert(t, "TestSignURL")
+
+	signed, err := SignURL("https://files.example.com/download/report.pdf?b=2&a=1", "secret", time.Hour, url.Values{"user": {"alice"}})
+	assert.IsNil(err)
+	u, err := url.Parse(signed)
+	assert.IsNil(err)
+	assert.Equal("alice", u.Query().Get("user"))
+	assert.NotEqual("", u.Query().Get(SignedURLExpires))
+
+	// 参数顺序变化不影响签名, host 也不参与签名
+	reordered := "http://internal:8080" + u.Path + "?" + reverseQuery(u.RawQuery)
+	assert.IsNil(VerifySignedURL(httptest.NewRequest(http.MethodGet, reordered, nil), "secret"))
+
+	err = VerifySignedURL(httptest.NewRequest(http.MethodGet, signed, nil), "other")
+	assert.Equal(true, errors.Is(err, ErrInvalidSignature))
+
+	tampered := strings.Replace(signed, "user=alice", "user=bob", 1)
+	err = VerifySignedURL(httptest.NewRequest(http.MethodGet, tampered, nil), "secret")
+	assert.Equal(true, errors.Is(err, ErrInvalidSignature))
+
+	tampered = strings.Replace(signed, "report.pdf", "secret.pdf", 1)
+	err = VerifySignedURL(httptest.NewRequest(http.MethodGet, tampered, nil), "secret")
+	assert.Equal(true, errors.Is(err, ErrInvalidSignature))
+
+	err = VerifySignedURL(httptest.NewRequest(http.MethodGet, "/download/report.pdf", nil), "secret")
+	assert.Equal(true, errors.Is(err, ErrInvalidSignature))
+
+	expired, err := SignURL("/download/report.pdf", "secret", -time.Minute, nil)
+	assert.IsNil(err)
+	err = VerifySignedURL(httptest.NewRequest(http.MethodGet, expired, nil), "secret")
+	assert.Equal(true, errors.Is(err, ErrSignatureExpired))
+
+	_, err = SignURL("/download/report.pdf", "", time.Hour, nil)
+	assert.Equal(true, errors.Is(err, ErrEmptyInput))
+}
+
+func TestSignedURLHandler(t *testing.T) {
+	assert := internal.NewAssert(t, "TestSignedURLHandler")
+
+	handler := SignedURLHandler("secret", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("file"))
+	}))
+	signed, err := SignURL("/download/report.pdf", "secret", time.Hour, url.Values{SignedURLIP: {"10.0.0.1"}})
+	assert.IsNil(err)
+
+	req := httptest.NewRequest(http.MethodGet, signed, nil)
+	req.RemoteAddr = "10.0.0.1:34567"
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(http.StatusOK, rec.Code)
+	assert.Equal("file", rec.Body.String())
+
+	req = httptest.NewRequest(http.MethodGet, signed, nil)
+	req.RemoteAddr = "10.0.0.2:34567"
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(http.StatusForbidden, rec.Code)
+
+	// IPv4 映射的 IPv6 地址和其他写法的 IPv6 地址按规范形式比较
+	req = httptest.NewRequest(http.MethodGet, signed, nil)
+	req.RemoteAddr = "[::ffff:10.0.0.1]:34567"
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(http.StatusOK, rec.Code)
+
+	signed6, err := SignURL("/download/report.pdf", "secret", time.Hour, url.Values{SignedURLIP: {"2001:DB8:0:0::1"}})
+	assert.IsNil(err)
+	req = httptest.NewRequest(http.MethodGet, signed6, nil)
+	req.RemoteAddr = "[2001:db8::1]:34567"
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(http.StatusOK, rec.Code)
+
+	req = httptest.NewRequest(http.MethodGet, signed, nil)
+	req.RemoteAddr = "[::1]:34567"
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(http.StatusForbidden, rec.Code)
+
+	// 默认不信任客户端发来的转发头
+	req = httptest.NewRequest(http.MethodGet, signed, nil)
+	req.RemoteAddr = "10.0.0.2:34567"
+	req.Header.Set("X-Real-IP", "10.0.0.1")
+	req.Header.Set("X-Forwarded-For", "10.0.0.1")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(http.StatusForbidden, rec.Code)
+
+	// 可信代理后面由代理设置 X-Real-IP
+	proxied := SignedURLHandlerWithOpt("secret", SignedURLOpt{ClientIP: IPGet}, http.NotFoundHandler())
+	req = httptest.NewRequest(http.MethodGet, signed, nil)
+	req.RemoteAddr = "192.168.0.1:80"
+	req.Header.Set("X-Real-IP", "10.0.0.1")
+	rec = httptest.NewRecorder()
+	proxied.ServeHTTP(rec, req)
+	assert.Equal(http.StatusNotFound, rec.Code)
+}
+
+func reverseQuery(raw string) string {
+	parts := strings.Split(raw, "&")
+	for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+		parts[i], parts[j] = parts[j], parts[i]
+	}
+	return strings.Join(parts, "&")
+}
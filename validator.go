@@ -28,6 +28,8 @@ var (
 	creditCardMatcher      *regexp.Regexp = regexp.MustCompile(`^(?:4[0-9]{12}(?:[0-9]{3})?|5[1-5][0-9]{14}|(222[1-9]|22[3-9][0-9]|2[3-6][0-9]{2}|27[01][0-9]|2720)[0-9]{12}|6(?:011|5[0-9][0-9])[0-9]{12}|3[47][0-9]{13}|3(?:0[0-5]|[68][0-9])[0-9]{11}|(?:2131|1800|35\\d{3})\\d{11}|6[27][0-9]{14})$`)
 	base64Matcher          *regexp.Regexp = regexp.MustCompile(`^(?:[A-Za-z0-9+\\/]{4})*(?:[A-Za-z0-9+\\/]{2}==|[A-Za-z0-9+\\/]{3}=|[A-Za-z0-9+\\/]{4})$`)
 	base64URLMatcher       *regexp.Regexp = regexp.MustCompile(`^([A-Za-z0-9_-]{4})*([A-Za-z0-9_-]{2}(==)?|[A-Za-z0-9_-]{3}=?)?$`)
+	base58Matcher          *regexp.Regexp = regexp.MustCompile(`^[1-9A-HJ-NP-Za-km-z]+$`)
+	base62Matcher          *regexp.Regexp = regexp.MustCompile(`^[0-9A-Za-z]+$`)
 	binMatcher             *regexp.Regexp = regexp.MustCompile(`^(0b)?[01]+$`)
 	hexMatcher             *regexp.Regexp = regexp.MustCompile(`^(#|0x|0X)?[0-9a-fA-F]+$`)
 	visaMatcher            *regexp.Regexp = regexp.MustCompile(`^4[0-9]{12}(?:[0-9]{3})?$`)
@@ -497,6 +499,31 @@ func IsBase64URL(v string) bool {
 	return base64URLMatcher.MatchString(v)
 }
 
+// IsBase32 check if a give string is a valid standard Base32 encoded string.
+func IsBase32(v string) bool {
+	return v != "" && IsEncoded(EncodingBase32, v)
+}
+
+// IsBase32Hex check if a give string is a valid Base32 encoded string with the extended hex alphabet.
+func IsBase32Hex(v string) bool {
+	return v != "" && IsEncoded(EncodingBase32Hex, v)
+}
+
+// IsBase58 check if a give string is a valid Base58 (Bitcoin alphabet) encoded string.
+func IsBase58(v string) bool {
+	return base58Matcher.MatchString(v)
+}
+
+// IsBase62 check if a give string is a valid Base62 encoded string.
+func IsBase62(v string) bool {
+	return base62Matcher.MatchString(v)
+}
+
+// IsBase85 check if a give string is a valid ascii85 encoded string.
+func IsBase85(v string) bool {
+	return v != "" && IsEncoded(EncodingBase85, v)
+}
+
 // IsJWT check if a give string is a valid JSON Web Token (JWT).
 // Play: https://go.dev/play/p/R6Op7heJbKI
 func IsJWT(v string) bool {
@@ -594,6 +594,31 @@ func TestIsBase64URL(t *testing.T) {
 	// assert.Equal(false, IsBase64URL(""))
 }
 
+func TestIsBaseN(t *testing.T) {
+	t.Parallel()
+	assert := internal.NewAssert(t, "TestIsBaseN")
+
+	assert.Equal(true, IsBase32("NBSWY3DP"))
+	assert.Equal(true, IsBase32("NBSWY3DPEE======"))
+	assert.Equal(false, IsBase32("NBSWY3DPEE"))
+	assert.Equal(false, IsBase32("nbswy3dp"))
+	assert.Equal(false, IsBase32(""))
+
+	assert.Equal(true, IsBase32Hex("D1IMOR3F"))
+	assert.Equal(false, IsBase32Hex("NBSWY3DP"))
+
+	assert.Equal(true, IsBase58("StV1DL6CwTryKyV"))
+	assert.Equal(false, IsBase58("0OIl"))
+	assert.Equal(false, IsBase58(""))
+
+	assert.Equal(true, IsBase62("T8dgcjRGkZ3aysdN"))
+	assert.Equal(false, IsBase62("abc-def"))
+
+	assert.Equal(true, IsBase85("BOu!rDZ"))
+	assert.Equal(false, IsBase85("BOu!rD~"))
+	assert.Equal(false, IsBase85(""))
+}
+
 func TestIsJWT(t *testing.T) {
 	t.Parallel()
 	assert := internal.NewAssert(t, "TestIsJWT")
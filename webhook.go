@@ -0,0 +1,221 @@
+package crab
+
+import (
+	"crypto/hmac"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// WebhookSignatureHeader 默认的签名 header
+	WebhookSignatureHeader = "Webhook-Signature"
+	// WebhookGitHubHeader GitHub 格式默认的签名 header
+	WebhookGitHubHeader = "X-Hub-Signature-256"
+	// defaultWebhookTolerance 默认允许的时间偏差, 与 Stripe 一致
+	defaultWebhookTolerance = 5 * time.Minute
+	// defaultWebhookMaxBody Middleware 默认读取的最大 body
+	defaultWebhookMaxBody = 1 << 20
+)
+
+// WebhookFormat webhook 签名 header 的格式
+type WebhookFormat string
+
+const (
+	// WebhookFormatStripe "t=时间戳,v1=签名[,v1=签名...]", 签名为 HMAC-SHA256("时间戳.body") 的 hex, 默认格式
+	WebhookFormatStripe WebhookFormat = "stripe"
+	// WebhookFormatGitHub "sha256=签名", 签名为 HMAC-SHA256(body) 的 hex
+	// 不包含时间戳, 无法检查时间偏差, 需要用 delivery id 等自行防重放
+	WebhookFormatGitHub WebhookFormat = "github"
+)
+
+// WebhookOpt WebhookSigner 配置
+type WebhookOpt struct {
+	// Format 签名格式, 默认 WebhookFormatStripe
+	Format WebhookFormat
+	// Secrets 签名密钥, 轮换期间可以同时配置多个; 签名时为每个密钥各生成一个签名, 验证时任一密钥匹配即可
+	Secrets []string
+	// Header 签名所在的 header, 默认 Webhook-Signature, GitHub 格式默认 X-Hub-Signature-256
+	Header string
+	// Tolerance 签名时间与当前时间允许的偏差, 默认 5 分钟, 小于 0 时不检查; GitHub 格式没有时间戳, 不检查
+	Tolerance time.Duration
+	// MaxBodySize Middleware 读取的最大 body 字节数, 默认 1 MiB
+	MaxBodySize int64
+}
+
+// WebhookSigner Stripe 或 GitHub 格式的 webhook 签名
+type WebhookSigner struct {
+	format    WebhookFormat
+	secrets   []string
+	header    string
+	tolerance time.Duration
+	maxBody   int64
+	now       func() time.Time
+}
+
+// NewWebhookSigner 创建 WebhookSigner, 至少需要一个密钥
+func NewWebhookSigner(opt WebhookOpt) (*WebhookSigner, error) {
+	if len(opt.Secrets) == 0 {
+		return nil, fmt.Errorf("%w: webhook secret", ErrEmptyInput)
+	}
+	for _, secret := range opt.Secrets {
+		if secret == "" {
+			return nil, fmt.Errorf("%w: webhook secret", ErrEmptyInput)
+		}
+	}
+	s := &WebhookSigner{
+		format:    opt.Format,
+		secrets:   opt.Secrets,
+		header:    opt.Header,
+		tolerance: opt.Tolerance,
+		maxBody:   opt.MaxBodySize,
+		now:       time.Now,
+	}
+	switch s.format {
+	case "":
+		s.format = WebhookFormatStripe
+	case WebhookFormatStripe, WebhookFormatGitHub:
+	default:
+		return nil, fmt.Errorf("%w: webhook format %q", ErrUnsupportedAlgorithm, opt.Format)
+	}
+	if s.header == "" {
+		s.header = WebhookSignatureHeader
+		if s.format == WebhookFormatGitHub {
+			s.header = WebhookGitHubHeader
+		}
+	}
+	if s.tolerance == 0 {
+		s.tolerance = defaultWebhookTolerance
+	}
+	if s.maxBody <= 0 {
+		s.maxBody = defaultWebhookMaxBody
+	}
+	return s, nil
+}
+
+// Sign 用当前时间为 body 签名, 返回 header 的值
+func (s *WebhookSigner) Sign(body []byte) string {
+	return s.SignAt(body, s.now())
+}
+
+// SignAt 用时间 t 为 body 签名, 返回 header 的值
+// GitHub 格式忽略 t, 且 header 只能携带一个签名, 只使用第一个密钥
+func (s *WebhookSigner) SignAt(body []byte, t time.Time) string {
+	if s.format == WebhookFormatGitHub {
+		return "sha256=" + HmacSha256(string(body), s.secrets[0])
+	}
+	ts := strconv.FormatInt(t.Unix(), 10)
+	parts := []string{"t=" + ts}
+	for _, secret := range s.secrets {
+		parts = append(parts, "v1="+webhookSignature(secret, ts, body))
+	}
+	return strings.Join(parts, ",")
+}
+
+// Verify 验证 header 中的签名, 签名不匹配返回 ErrInvalidSignature, 超出时间偏差返回 ErrSignatureExpired
+func (s *WebhookSigner) Verify(header string, body []byte) error {
+	if s.format == WebhookFormatGitHub {
+		value, ok := strings.CutPrefix(header, "sha256=")
+		sig, err := hex.DecodeString(value)
+		if !ok || err != nil || len(sig) == 0 {
+			return fmt.Errorf("%w: malformed signature header", ErrInvalidSignature)
+		}
+		for _, secret := range s.secrets {
+			expected, _ := hex.DecodeString(HmacSha256(string(body), secret))
+			if hmac.Equal(expected, sig) {
+				return nil
+			}
+		}
+		return ErrInvalidSignature
+	}
+	ts, sigs, err := parseWebhookHeader(header)
+	if err != nil {
+		return err
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: malformed timestamp", ErrInvalidSignature)
+	}
+	if s.tolerance > 0 {
+		if age := s.now().Sub(time.Unix(unix, 0)); age > s.tolerance || age < -s.tolerance {
+			return fmt.Errorf("%w: timestamp is %s away", ErrSignatureExpired, age.Abs())
+		}
+	}
+
+	for _, secret := range s.secrets {
+		expected, _ := hex.DecodeString(webhookSignature(secret, ts, body))
+		for _, sig := range sigs {
+			if hmac.Equal(expected, sig) {
+				return nil
+			}
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// SignRequest 读取 req 的 body 签名并设置签名 header, body 会被重新设置以便发送
+func (s *WebhookSigner) SignRequest(req *http.Request) error {
+	body, err := readRequestBody(req, -1)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(s.header, s.Sign(body))
+	return nil
+}
+
+// VerifyRequest 验证 req 的签名, 验证后 body 可以再次读取, body 超过 MaxBodySize 时返回 ErrBodyTooLarge
+func (s *WebhookSigner) VerifyRequest(req *http.Request) error {
+	body, err := readRequestBody(req, s.maxBody)
+	if err != nil {
+		return err
+	}
+	return s.Verify(req.Header.Get(s.header), body)
+}
+
+// Middleware 验证请求签名的 net/http 中间件, 验证失败时返回 401, body 过大时返回 413
+// 响应中不包含失败的具体原因
+func (s *WebhookSigner) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := s.VerifyRequest(r); err != nil {
+			code := http.StatusUnauthorized
+			if errors.Is(err, ErrBodyTooLarge) {
+				code = http.StatusRequestEntityTooLarge
+			}
+			http.Error(w, http.StatusText(code), code)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func webhookSignature(secret, ts string, body []byte) string {
+	return HmacSha256(ts+"."+string(body), secret)
+}
+
+// parseWebhookHeader 解析 "t=...,v1=...", 忽略未知的字段
+func parseWebhookHeader(header string) (string, [][]byte, error) {
+	var ts string
+	var sigs [][]byte
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			ts = value
+		case "v1":
+			if sig, err := hex.DecodeString(value); err == nil {
+				sigs = append(sigs, sig)
+			}
+		}
+	}
+	if ts == "" || len(sigs) == 0 {
+		return "", nil, fmt.Errorf("%w: malformed signature header", ErrInvalidSignature)
+	}
+	return ts, sigs, nil
+}
@@ -0,0 +1,118 @@
+package crab
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/serialt/crab/internal"
+)
+
+func TestWebhookSigner(t *testing.T) {
+	assert := internal.NewAssert(t, "TestWebhookSigner")
+
+	_, err := NewWebhookSigner(WebhookOpt{})
+	assert.Equal(true, errors.Is(err, ErrEmptyInput))
+
+	signer, err := NewWebhookSigner(WebhookOpt{Secrets: []string{"whsec_new"}})
+	assert.IsNil(err)
+	body := []byte(`{"event":"ping"}`)
+	now := time.Unix(1700000000, 0)
+	signer.now = func() time.Time { return now }
+
+	header := signer.Sign(body)
+	assert.Equal("t=1700000000,v1="+HmacSha256(`1700000000.{"event":"ping"}`, "whsec_new"), header)
+	assert.IsNil(signer.Verify(header, body))
+
+	err = signer.Verify(header, []byte(`{"event":"pong"}`))
+	assert.Equal(true, errors.Is(err, ErrInvalidSignature))
+	err = signer.Verify("v1=abcd", body)
+	assert.Equal(true, errors.Is(err, ErrInvalidSignature))
+
+	err = signer.Verify(signer.SignAt(body, now.Add(-10*time.Minute)), body)
+	assert.Equal(true, errors.Is(err, ErrSignatureExpired))
+	err = signer.Verify(signer.SignAt(body, now.Add(10*time.Minute)), body)
+	assert.Equal(true, errors.Is(err, ErrSignatureExpired))
+
+	// 轮换期间: 旧发送方只用旧密钥签名, 接收方同时接受新旧密钥
+	oldSigner, _ := NewWebhookSigner(WebhookOpt{Secrets: []string{"whsec_old"}})
+	rotating, _ := NewWebhookSigner(WebhookOpt{Secrets: []string{"whsec_new", "whsec_old"}})
+	rotating.now = signer.now
+	assert.IsNil(rotating.Verify(oldSigner.SignAt(body, now), body))
+	assert.IsNil(signer.Verify(rotating.Sign(body), body))
+	assert.Equal(3, len(strings.Split(rotating.Sign(body), ",")))
+
+	noExpiry, _ := NewWebhookSigner(WebhookOpt{Secrets: []string{"whsec_new"}, Tolerance: -1})
+	assert.IsNil(noExpiry.Verify(signer.SignAt(body, now.Add(-time.Hour)), body))
+}
+
+func TestWebhookMiddleware(t *testing.T) {
+	assert := internal.NewAssert(t, "TestWebhookMiddleware")
+
+	signer, err := NewWebhookSigner(WebhookOpt{Secrets: []string{"secret"}, Header: "X-Signature", MaxBodySize: 64})
+	assert.IsNil(err)
+	handler := signer.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader("payload"))
+	assert.IsNil(signer.SignRequest(req))
+	assert.NotEqual("", req.Header.Get("X-Signature"))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(http.StatusOK, rec.Code)
+	assert.Equal("payload", rec.Body.String())
+
+	req = httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader("tampered"))
+	req.Header.Set("X-Signature", signer.Sign([]byte("payload")))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(http.StatusUnauthorized, rec.Code)
+	// 不向调用方暴露失败的原因
+	assert.Equal(http.StatusText(http.StatusUnauthorized)+"\n", rec.Body.String())
+
+	large := strings.Repeat("a", 65)
+	req = httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader(large))
+	req.Header.Set("X-Signature", signer.Sign([]byte(large)))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(http.StatusRequestEntityTooLarge, rec.Code)
+	err = signer.VerifyRequest(httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader(large)))
+	assert.Equal(true, errors.Is(err, ErrBodyTooLarge))
+}
+
+func TestWebhookGitHub(t *testing.T) {
+	assert := internal.NewAssert(t, "TestWebhookGitHub")
+
+	_, err := NewWebhookSigner(WebhookOpt{Secrets: []string{"secret"}, Format: "slack"})
+	assert.Equal(true, errors.Is(err, ErrUnsupportedAlgorithm))
+
+	// GitHub 文档中的示例
+	signer, err := NewWebhookSigner(WebhookOpt{Secrets: []string{"It's a Secret to Everybody"}, Format: WebhookFormatGitHub})
+	assert.IsNil(err)
+	body := []byte("Hello, World!")
+	header := signer.Sign(body)
+	assert.Equal("sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17", header)
+	assert.IsNil(signer.Verify(header, body))
+
+	err = signer.Verify(header, []byte("Hello, World?"))
+	assert.Equal(true, errors.Is(err, ErrInvalidSignature))
+	err = signer.Verify(strings.TrimPrefix(header, "sha256="), body)
+	assert.Equal(true, errors.Is(err, ErrInvalidSignature))
+	err = signer.Verify("sha256=", body)
+	assert.Equal(true, errors.Is(err, ErrInvalidSignature))
+
+	// 轮换期间接受任一密钥
+	rotating, _ := NewWebhookSigner(WebhookOpt{Secrets: []string{"new", "It's a Secret to Everybody"}, Format: WebhookFormatGitHub})
+	assert.IsNil(rotating.Verify(header, body))
+
+	req := httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader("Hello, World!"))
+	assert.IsNil(signer.SignRequest(req))
+	assert.Equal(header, req.Header.Get(WebhookGitHubHeader))
+	assert.IsNil(signer.VerifyRequest(req))
+}